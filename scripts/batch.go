@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+)
+
+// 批量获取的结果状态
+const (
+	batchFetched = "fetched"
+	batchSkipped = "skipped"
+	batchFailed  = "failed"
+)
+
+// batchEntry 批量列表中的一条记录
+type batchEntry struct {
+	line      int    // 行号 (从1开始)
+	raw       string // 原始内容
+	mediaType string
+	mediaID   string
+	external  *externalID // 外部ID，获取前通过TMDB find 接口解析为 mediaType/mediaID
+}
+
+// batchResult 单条记录的处理结果
+type batchResult struct {
+	entry  batchEntry
+	status string
+	reason string
+}
+
+// readBatchEntries 读取批量列表，空行和 # 开头的注释行会被忽略
+// 无法解析的行直接作为失败结果返回
+func readBatchEntries(r io.Reader) ([]batchEntry, []batchResult, error) {
+	var entries []batchEntry
+	var invalid []batchResult
+
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		entry := batchEntry{line: lineNo, raw: line}
+		if ext, ok, err := parseExternalID(line); ok {
+			if err != nil {
+				invalid = append(invalid, batchResult{entry: entry, status: batchFailed, reason: err.Error()})
+				continue
+			}
+			entry.external = &ext
+			entries = append(entries, entry)
+			continue
+		}
+
+		mediaType, mediaID, err := parseMediaRef(line)
+		if err != nil {
+			invalid = append(invalid, batchResult{entry: entry, status: batchFailed, reason: err.Error()})
+			continue
+		}
+		entry.mediaType, entry.mediaID = mediaType, mediaID
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("读取列表失败: %v", err)
+	}
+
+	return entries, invalid, nil
+}
+
+// fetchBatch 使用工作池并发获取列表中的记录，已存在的目录和重复记录会被跳过
+// 并发数由配置中的 workers 决定，请求速率由共享的速率限制器控制
+func (f *TMDBFetcher) fetchBatch(entries []batchEntry) []batchResult {
+	results := make([]batchResult, len(entries))
+	seen := make(map[string]bool)
+	var pending []int
+
+	for i, entry := range entries {
+		// 外部ID需要先解析，解析后与其他记录一起去重
+		if entry.external != nil {
+			mediaType, mediaID, err := f.findByExternalID(*entry.external, "")
+			if err != nil {
+				results[i].entry = entry
+				results[i].status, results[i].reason = batchFailed, err.Error()
+				continue
+			}
+			entry.mediaType, entry.mediaID = mediaType, mediaID
+			entries[i] = entry
+		}
+		results[i].entry = entry
+
+		key := entry.mediaType + "/" + entry.mediaID
+		if seen[key] {
+			results[i].status, results[i].reason = batchSkipped, "列表中重复"
+			continue
+		}
+		seen[key] = true
+
+		if checkDirectoryExists(f.mediaDir(entry.mediaType, entry.mediaID)) {
+			results[i].status, results[i].reason = batchSkipped, "目录已存在"
+			continue
+		}
+		pending = append(pending, i)
+	}
+
+	workers := f.config.Workers
+	if workers > len(pending) {
+		workers = len(pending)
+	}
+	if len(pending) > 0 {
+		fmt.Printf("\n共 %d 条待获取，并发数: %d，速率上限: %.1f 次/秒\n", len(pending), workers, f.config.RequestsPerSecond)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	var finished int32
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				entry := entries[i]
+				if err := f.fetchAndSave(entry.mediaType, entry.mediaID); err != nil {
+					results[i].status, results[i].reason = batchFailed, err.Error()
+				} else {
+					results[i].status = batchFetched
+				}
+				n := atomic.AddInt32(&finished, 1)
+				fmt.Printf("\n[%d/%d] %s/%s: %s\n", n, len(pending), entry.mediaType, entry.mediaID, results[i].status)
+			}
+		}()
+	}
+
+	for _, i := range pending {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// printBatchSummary 打印批量获取的汇总表格，返回失败条数
+func printBatchSummary(results []batchResult) int {
+	counts := make(map[string]int)
+	for _, r := range results {
+		counts[r.status]++
+	}
+
+	fmt.Println("\n" + strings.Repeat("=", 60))
+	fmt.Println("批量获取汇总")
+	fmt.Println(strings.Repeat("=", 60))
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "行号\t状态\t类型\tID\t说明")
+	for _, r := range results {
+		mediaType, mediaID := r.entry.mediaType, r.entry.mediaID
+		if mediaType == "" {
+			mediaType, mediaID = "-", r.entry.raw
+		}
+		reason := r.reason
+		if reason == "" {
+			reason = "-"
+		}
+		// 错误信息可能包含换行，只保留第一行
+		reason = strings.SplitN(reason, "\n", 2)[0]
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", r.entry.line, r.status, mediaType, mediaID, reason)
+	}
+	w.Flush()
+
+	fmt.Printf("\n共 %d 条: 获取 %d, 跳过 %d, 失败 %d\n",
+		len(results), counts[batchFetched], counts[batchSkipped], counts[batchFailed])
+
+	return counts[batchFailed]
+}
+
+// runBatchFile 从文件读取列表并批量获取，path为 "-" 时从标准输入读取
+func (f *TMDBFetcher) runBatchFile(path string) (int, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return 0, fmt.Errorf("打开列表文件失败: %v", err)
+		}
+		defer file.Close()
+		r = file
+	}
+
+	entries, invalid, err := readBatchEntries(r)
+	if err != nil {
+		return 0, err
+	}
+
+	results := append(invalid, f.fetchBatch(entries)...)
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].entry.line < results[j].entry.line
+	})
+	return printBatchSummary(results), nil
+}
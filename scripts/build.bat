@@ -13,37 +13,37 @@ REM Windows AMD64
 echo Compiling Windows AMD64...
 set GOOS=windows
 set GOARCH=amd64
-go build -o "%OUTPUT_DIR%\%APP_NAME%-windows-amd64.exe" tmdb_manager.go
+go build -o "%OUTPUT_DIR%\%APP_NAME%-windows-amd64.exe" .
 
 REM Windows ARM64
 echo Compiling Windows ARM64...
 set GOOS=windows
 set GOARCH=arm64
-go build -o "%OUTPUT_DIR%\%APP_NAME%-windows-arm64.exe" tmdb_manager.go
+go build -o "%OUTPUT_DIR%\%APP_NAME%-windows-arm64.exe" .
 
 REM Linux AMD64
 echo Compiling Linux AMD64...
 set GOOS=linux
 set GOARCH=amd64
-go build -o "%OUTPUT_DIR%\%APP_NAME%-linux-amd64" tmdb_manager.go
+go build -o "%OUTPUT_DIR%\%APP_NAME%-linux-amd64" .
 
 REM Linux ARM64
 echo Compiling Linux ARM64...
 set GOOS=linux
 set GOARCH=arm64
-go build -o "%OUTPUT_DIR%\%APP_NAME%-linux-arm64" tmdb_manager.go
+go build -o "%OUTPUT_DIR%\%APP_NAME%-linux-arm64" .
 
 REM macOS AMD64 (Intel)
 echo Compiling macOS AMD64...
 set GOOS=darwin
 set GOARCH=amd64
-go build -o "%OUTPUT_DIR%\%APP_NAME%-macos-amd64" tmdb_manager.go
+go build -o "%OUTPUT_DIR%\%APP_NAME%-macos-amd64" .
 
 REM macOS ARM64 (Apple Silicon)
 echo Compiling macOS ARM64...
 set GOOS=darwin
 set GOARCH=arm64
-go build -o "%OUTPUT_DIR%\%APP_NAME%-macos-arm64" tmdb_manager.go
+go build -o "%OUTPUT_DIR%\%APP_NAME%-macos-arm64" .
 
 echo.
 echo Build completed!
@@ -14,27 +14,27 @@ APP_NAME="tmdb-manager"
 
 # Windows AMD64
 echo "编译 Windows AMD64..."
-GOOS=windows GOARCH=amd64 go build -o "${OUTPUT_DIR}/${APP_NAME}-windows-amd64.exe" tmdb_manager.go
+GOOS=windows GOARCH=amd64 go build -o "${OUTPUT_DIR}/${APP_NAME}-windows-amd64.exe" .
 
 # Windows ARM64
 echo "编译 Windows ARM64..."
-GOOS=windows GOARCH=arm64 go build -o "${OUTPUT_DIR}/${APP_NAME}-windows-arm64.exe" tmdb_manager.go
+GOOS=windows GOARCH=arm64 go build -o "${OUTPUT_DIR}/${APP_NAME}-windows-arm64.exe" .
 
 # Linux AMD64
 echo "编译 Linux AMD64..."
-GOOS=linux GOARCH=amd64 go build -o "${OUTPUT_DIR}/${APP_NAME}-linux-amd64" tmdb_manager.go
+GOOS=linux GOARCH=amd64 go build -o "${OUTPUT_DIR}/${APP_NAME}-linux-amd64" .
 
 # Linux ARM64
 echo "编译 Linux ARM64..."
-GOOS=linux GOARCH=arm64 go build -o "${OUTPUT_DIR}/${APP_NAME}-linux-arm64" tmdb_manager.go
+GOOS=linux GOARCH=arm64 go build -o "${OUTPUT_DIR}/${APP_NAME}-linux-arm64" .
 
 # macOS AMD64 (Intel)
 echo "编译 macOS AMD64..."
-GOOS=darwin GOARCH=amd64 go build -o "${OUTPUT_DIR}/${APP_NAME}-macos-amd64" tmdb_manager.go
+GOOS=darwin GOARCH=amd64 go build -o "${OUTPUT_DIR}/${APP_NAME}-macos-amd64" .
 
 # macOS ARM64 (Apple Silicon)
 echo "编译 macOS ARM64..."
-GOOS=darwin GOARCH=arm64 go build -o "${OUTPUT_DIR}/${APP_NAME}-macos-arm64" tmdb_manager.go
+GOOS=darwin GOARCH=arm64 go build -o "${OUTPUT_DIR}/${APP_NAME}-macos-arm64" .
 
 echo ""
 echo "✓ 编译完成！"
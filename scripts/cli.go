@@ -0,0 +1,1074 @@
+package main
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"tmdb-manager/updater"
+)
+
+// 命令行模式的退出码
+const (
+	exitOK       = 0 // 成功
+	exitError    = 1 // 执行失败
+	exitUsage    = 2 // 参数错误
+	exitConflict = 3 // 执行成功，但有需要手动处理的冲突或需要核对的修改
+)
+
+const cliUsage = `用法: tmdb-manager <命令> [参数]
+
+不带任何参数运行时进入交互菜单。
+
+命令:
+  fetch <movie|tv> <id>...   获取电影/电视剧数据并保存到 tmdb_config
+                             id 也可以是TMDB/IMDb链接、tv/95480 或外部ID: tt1234567、
+                             tvdb:12345、wikidata:Q123456 等，此时可省略媒体类型
+  search <movie|tv> <关键字>  按标题搜索，列出候选条目的TMDB ID
+  batch [列表文件|-]          从列表文件或标准输入批量获取数据
+  refresh <movie|tv> <id>... 重新获取TMDB数据并与本地修改三方合并
+  diff <movie|tv> <id> [文件] 显示TMDB原始快照与本地维护数据的字段差异
+  overlay convert <movie|tv> <id>...
+                             将完整数据文件转换为只包含修改字段的覆盖文件 (overlay.json)
+  overlay apply <movie|tv> <id>...
+                             获取最新TMDB数据并应用覆盖文件，输出完整数据文件
+  lint                       检查 tmdb_config 中全部数据文件的格式和内容
+  pack                       生成发布包 tmdb_config.tar.gz 及校验文件、manifest.json
+  keygen <私钥文件>          生成用于签名发布包的 ed25519 密钥对
+  sign                       使用私钥对发布包和 manifest.json 签名
+  verify [压缩包]            使用公钥离线校验下载的发布包或增量包
+  apply-delta <增量包>       把增量包应用到上一个版本的数据目录，并按新版本的 manifest.json 校验
+  export nfo [<movie|tv> <id>...]
+                             导出 Kodi/Jellyfin 使用的 NFO 文件，不指定条目时导出全部条目
+  export sqlite              把全部条目导出为一个带索引的 SQLite 数据库
+  update                     从发布地址下载最新的数据，校验后安装到本地目录，保留上一个版本用于回滚
+  serve                      以TMDB API的格式在本地提供 tmdb_config 中的数据
+  proxy                      代理TMDB API，把 tmdb_config 中维护的修改合并到响应中
+  watch                      检查维护的条目自上次检查以来在TMDB上的标题、简介和翻译修改
+  sync                       从主库同步最新代码
+  submit                     提交 tmdb_config 的修改到PR
+  help                       显示本帮助
+
+fetch/batch/refresh/overlay/search/watch/proxy 参数:
+  --config <路径>   配置文件路径 (默认: ../cli/config.json)
+  --output <目录>   元数据保存目录 (默认: ../tmdb_config)
+
+diff 参数:
+  --output <目录>   元数据保存目录 (默认: ../tmdb_config)
+  --all             比较快照中的全部文件 (默认只比较 details.json)
+
+serve 参数:
+  --output <目录>   元数据保存目录 (默认: ../tmdb_config)
+  --addr <地址>     监听地址 (默认: 127.0.0.1:8080)
+
+proxy 额外参数:
+  --addr <地址>     监听地址 (默认: 127.0.0.1:8080)
+  --cache-dir <目录> TMDB响应的缓存目录，为空时不缓存 (默认: ../tmdb_cache)
+  --cache-ttl <时长> 缓存有效期，如 30m、12h (默认: 24h)
+
+pack 参数:
+  --output <目录>   元数据保存目录 (默认: ../tmdb_config)
+  --dest <目录>     发布包的输出目录 (默认: ..)
+  --version <版本>  写入 manifest.json 的版本号 (默认: 当前的北京时间，如 202401021504)
+  --repo <目录>     项目根目录，用于查找每个条目最后修改的提交，为空时不查找 (默认: ..)
+  --base <路径>     上一个版本的 manifest.json，指定时同时生成增量包 tmdb_config.delta.tar.gz
+
+sign 参数:
+  --key <路径>      私钥文件 (默认: 读取环境变量 TMDB_SIGNING_KEY)
+  --dest <目录>     发布包所在目录 (默认: ..)
+
+verify 参数:
+  --pubkey <公钥>   base64 编码的公钥或公钥文件路径 (必需)
+
+apply-delta 参数:
+  --target <目录>   上一个版本解压出的 tmdb_config 目录 (必需)
+  --manifest <路径> 新版本的 manifest.json (默认: 增量包所在目录中的 manifest.json)
+  --pubkey <公钥>   同时校验增量包和 manifest.json 的签名 (默认: 不校验签名)
+
+export nfo 参数:
+  --output <目录>   元数据保存目录 (默认: ../tmdb_config)
+  --dest <目录>     NFO 文件的输出目录 (默认: ../tmdb_nfo)
+  --country <地区>  分级使用的地区，没有该地区的分级时使用 US (默认: CN)
+
+export sqlite 参数:
+  --output <目录>   元数据保存目录 (默认: ../tmdb_config)
+  --dest <路径>     数据库文件，已存在时整体替换 (默认: ../tmdb_config.db)
+
+update 参数:
+  --feed <地址>     发布地址，其下有 manifest.json 和压缩包 (默认: 主库最新 Release 的下载地址)
+  --dir <目录>      安装目录，数据位于其中的 current/tmdb_config (默认: ../tmdb_release)
+  --pubkey <公钥>   同时校验 manifest.json 和压缩包的签名 (默认: 只校验 SHA256)
+  --check           只检查是否有新版本，不下载
+  --rollback        回滚到上一个版本，再次回滚即可恢复
+
+lint 参数:
+  --output <目录>   元数据保存目录 (默认: ../tmdb_config)
+  --format <格式>   输出格式: text (默认，每行 "文件: 级别: 说明") 或 json
+
+watch 额外参数:
+  --state <路径>    记录上次检查时间的状态文件 (默认: ../cli/watch_state.json)
+  --days <天数>     首次检查的条目回溯的天数 (默认: 14)
+  --all-languages   报告全部语言的修改 (默认只报告配置语言的翻译和中文地区的别名)
+
+search 额外参数:
+  --page <页码>     结果页码 (默认: 1)
+
+overlay apply 额外参数:
+  --dest <目录>     完整数据文件的输出目录 (默认: ../tmdb_expanded)
+
+batch 额外参数:
+  --workers <数量>  并发数 (默认: 配置文件中的 workers，未配置时为 4)
+  --rps <数值>      每秒最多请求数 (默认: 配置文件中的 requests_per_second，未配置时为 20)
+
+sync/submit 参数:
+  --repo <目录>     项目根目录 (默认: ..)
+  --yes             对所有确认提示自动回答 y
+
+submit 额外参数:
+  --branch <名称>   新建分支名称 (默认: 自动生成)
+  --existing        推送到当前分支（提交到已有的PR）
+  --message <信息>  提交信息 (默认: Update TMDB config metadata)
+  --open            完成后在浏览器中打开PR链接
+
+示例:
+  tmdb-manager fetch movie 842675
+  tmdb-manager fetch tv 95480 --output ./tmdb_config
+  tmdb-manager fetch tt1234567 tvdb:81189
+  tmdb-manager fetch https://www.themoviedb.org/tv/95480/season/2
+  tmdb-manager search movie 流浪地球
+  tmdb-manager batch list.txt
+  cat list.txt | tmdb-manager batch -
+  tmdb-manager refresh tv 95480
+  tmdb-manager diff movie 842675
+  tmdb-manager overlay convert tv 37854
+  tmdb-manager overlay apply tv 37854 --dest ./tmdb_expanded
+  tmdb-manager lint --format json
+  tmdb-manager pack --dest ./dist
+  tmdb-manager pack --dest ./dist --base ./previous/manifest.json
+  tmdb-manager keygen release.key
+  tmdb-manager sign --key release.key --dest ./dist
+  tmdb-manager verify ./tmdb_config.tar.gz --pubkey release.key.pub
+  tmdb-manager apply-delta ./tmdb_config.delta.tar.gz --target ./media-saber-ctmd-main/tmdb_config
+  tmdb-manager export nfo --dest ./nfo
+  tmdb-manager export nfo tv 95480 --country US
+  tmdb-manager export sqlite --dest ./tmdb_config.db
+  tmdb-manager update --pubkey release.key.pub
+  tmdb-manager update --feed http://127.0.0.1:8000 --dir ./tmdb_release
+  tmdb-manager update --rollback
+  tmdb-manager serve --addr 0.0.0.0:8080
+  tmdb-manager proxy --cache-ttl 6h
+  tmdb-manager watch --days 30
+  tmdb-manager sync --yes
+  tmdb-manager submit --branch fix-95480 --message "Fix 95480 title" --yes
+`
+
+// runCLI 解析并执行子命令，返回进程退出码
+func runCLI(args []string) int {
+	name, rest := args[0], args[1:]
+
+	switch name {
+	case "fetch":
+		return cmdFetch(rest)
+	case "search":
+		return cmdSearch(rest)
+	case "batch":
+		return cmdBatch(rest)
+	case "refresh":
+		return cmdRefresh(rest)
+	case "diff":
+		return cmdDiff(rest)
+	case "overlay":
+		return cmdOverlay(rest)
+	case "lint":
+		return cmdLint(rest)
+	case "pack":
+		return cmdPack(rest)
+	case "keygen":
+		return cmdKeygen(rest)
+	case "sign":
+		return cmdSign(rest)
+	case "verify":
+		return cmdVerify(rest)
+	case "apply-delta":
+		return cmdApplyDelta(rest)
+	case "export":
+		return cmdExport(rest)
+	case "update":
+		return cmdUpdate(rest)
+	case "serve":
+		return cmdServe(rest)
+	case "proxy":
+		return cmdProxy(rest)
+	case "watch":
+		return cmdWatch(rest)
+	case "sync":
+		return cmdSync(rest)
+	case "submit":
+		return cmdSubmit(rest)
+	case "help", "-h", "--help":
+		fmt.Print(cliUsage)
+		return exitOK
+	default:
+		fmt.Fprintf(os.Stderr, "未知命令: %s\n\n", name)
+		fmt.Fprint(os.Stderr, cliUsage)
+		return exitUsage
+	}
+}
+
+// newFlagSet 创建子命令参数解析器
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "%s 的参数:\n", name)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseArgs 解析参数，允许参数与位置参数混排，返回全部位置参数
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// usageError 打印参数错误并返回对应退出码
+func usageError(format string, a ...interface{}) int {
+	fmt.Fprintf(os.Stderr, "参数错误: "+format+"\n", a...)
+	fmt.Fprintln(os.Stderr, "运行 'tmdb-manager help' 查看用法")
+	return exitUsage
+}
+
+// parseError 将参数解析错误转换为退出码
+func parseError(err error) int {
+	if err == flag.ErrHelp {
+		return exitOK
+	}
+	return exitUsage
+}
+
+// failed 打印执行错误并返回对应退出码
+func failed(err error) int {
+	fmt.Fprintf(os.Stderr, "\n错误: %v\n", err)
+	return exitError
+}
+
+// cmdFetch fetch子命令: 获取一个或多个同类型媒体的数据
+func cmdFetch(args []string) int {
+	fs := newFlagSet("fetch")
+	configPath := fs.String("config", defaultConfigPath, "配置文件路径")
+	outputDir := fs.String("output", defaultOutputDir, "元数据保存目录")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return parseError(err)
+	}
+
+	mediaType, ids, code := parseTypedIDs("fetch", positional)
+	if code != exitOK {
+		return code
+	}
+
+	fetcher, err := NewTMDBFetcher(*configPath, *outputDir)
+	if err != nil {
+		return failed(err)
+	}
+
+	for _, input := range ids {
+		resolvedType, id, err := fetcher.resolveMediaID(mediaType, input)
+		if err == nil {
+			err = fetcher.fetchAndSave(resolvedType, id)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "\n错误: %s: %v\n", input, err)
+			code = exitError
+		}
+	}
+	return code
+}
+
+// parseTypedIDs 解析 "<movie|tv> <id>..." 形式的位置参数
+// 全部为链接、"tv/95480" 或外部ID (tt1234567、tvdb:12345 等) 时可以省略媒体类型，由输入本身或查找结果决定
+func parseTypedIDs(command string, positional []string) (string, []string, int) {
+	if len(positional) == 0 {
+		return "", nil, usageError("%s 需要媒体类型和至少一个ID，例如: %s movie 842675", command, command)
+	}
+
+	mediaType := strings.ToLower(positional[0])
+	if mediaType == "movie" || mediaType == "tv" {
+		if len(positional) < 2 {
+			return "", nil, usageError("%s 需要至少一个ID，例如: %s %s 842675", command, command, mediaType)
+		}
+		return mediaType, positional[1:], exitOK
+	}
+
+	for _, p := range positional {
+		if !isMediaRef(p) {
+			return "", nil, usageError("无效的媒体类型 '%s'，可选: movie, tv (链接和外部ID可省略媒体类型)", positional[0])
+		}
+	}
+	return "", positional, exitOK
+}
+
+// cmdSearch search子命令: 按标题搜索并列出候选条目
+func cmdSearch(args []string) int {
+	fs := newFlagSet("search")
+	configPath := fs.String("config", defaultConfigPath, "配置文件路径")
+	outputDir := fs.String("output", defaultOutputDir, "元数据保存目录")
+	page := fs.Int("page", 1, "结果页码")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return parseError(err)
+	}
+
+	if len(positional) < 2 {
+		return usageError("search 需要媒体类型和关键字，例如: search movie 流浪地球")
+	}
+	mediaType, query := strings.ToLower(positional[0]), strings.Join(positional[1:], " ")
+	if mediaType != "movie" && mediaType != "tv" {
+		return usageError("无效的媒体类型 '%s'，可选: movie, tv", positional[0])
+	}
+	if *page < 1 {
+		return usageError("--page 必须大于 0")
+	}
+
+	fetcher, err := NewTMDBFetcher(*configPath, *outputDir)
+	if err != nil {
+		return failed(err)
+	}
+
+	result, err := fetcher.searchMedia(mediaType, query, *page)
+	if err != nil {
+		return failed(err)
+	}
+	if len(result.results) == 0 {
+		fmt.Println("没有找到匹配的结果")
+		return exitError
+	}
+	fmt.Printf("\n搜索结果 (第 %d/%d 页):\n", result.page, result.totalPages)
+	printSearchResults(result.results)
+	return exitOK
+}
+
+// cmdRefresh refresh子命令: 重新获取已有条目并保留本地修改
+func cmdRefresh(args []string) int {
+	fs := newFlagSet("refresh")
+	configPath := fs.String("config", defaultConfigPath, "配置文件路径")
+	outputDir := fs.String("output", defaultOutputDir, "元数据保存目录")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return parseError(err)
+	}
+
+	mediaType, ids, code := parseTypedIDs("refresh", positional)
+	if code != exitOK {
+		return code
+	}
+
+	fetcher, err := NewTMDBFetcher(*configPath, *outputDir)
+	if err != nil {
+		return failed(err)
+	}
+
+	for _, input := range ids {
+		resolvedType, id, err := fetcher.resolveMediaID(mediaType, input)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "\n错误: %s: %v\n", input, err)
+			code = exitError
+			continue
+		}
+		results, err := fetcher.refreshMedia(resolvedType, id)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "\n错误: %s/%s: %v\n", resolvedType, id, err)
+			code = exitError
+			continue
+		}
+		if printRefreshReport(resolvedType, id, results) > 0 && code == exitOK {
+			code = exitConflict
+		}
+	}
+	return code
+}
+
+// cmdDiff diff子命令: 显示快照与本地维护数据之间的字段差异
+func cmdDiff(args []string) int {
+	fs := newFlagSet("diff")
+	outputDir := fs.String("output", defaultOutputDir, "元数据保存目录")
+	all := fs.Bool("all", false, "比较快照中的全部文件")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return parseError(err)
+	}
+
+	if len(positional) < 2 {
+		return usageError("diff 需要媒体类型和ID，例如: diff movie 842675")
+	}
+	keys, code := parseLocalKeys("diff", positional[:2])
+	if code != exitOK {
+		return code
+	}
+	mediaType, mediaID, relPaths := keys[0].mediaType, keys[0].mediaID, positional[2:]
+	for _, relPath := range relPaths {
+		if !updater.IsSafeRelPath(relPath) {
+			return usageError("无效的文件路径 '%s'，应为条目目录中的相对路径，如 season/1/details.json", relPath)
+		}
+	}
+	if len(relPaths) == 0 && !*all {
+		relPaths = []string{"details.json"}
+	}
+
+	// 只读取本地文件，不需要API Key
+	local := &TMDBFetcher{outputDir: *outputDir}
+	if _, err := local.diffSnapshot(mediaType, mediaID, relPaths); err != nil {
+		return failed(err)
+	}
+	return exitOK
+}
+
+// cmdOverlay overlay子命令: 转换和展开覆盖文件
+func cmdOverlay(args []string) int {
+	if len(args) == 0 {
+		return usageError("overlay 需要子命令 convert 或 apply，例如: overlay convert tv 37854")
+	}
+	action := args[0]
+	if action != "convert" && action != "apply" {
+		return usageError("未知的 overlay 子命令 '%s'，可选: convert, apply", action)
+	}
+
+	fs := newFlagSet("overlay " + action)
+	configPath := fs.String("config", defaultConfigPath, "配置文件路径")
+	outputDir := fs.String("output", defaultOutputDir, "元数据保存目录")
+	destDir := fs.String("dest", defaultExpandDir, "完整数据文件的输出目录 (仅 apply)")
+	positional, err := parseArgs(fs, args[1:])
+	if err != nil {
+		return parseError(err)
+	}
+
+	if len(positional) < 2 {
+		return usageError("overlay %s 需要媒体类型和至少一个ID，例如: overlay %s tv 37854", action, action)
+	}
+	keys, code := parseLocalKeys("overlay "+action, positional)
+	if code != exitOK {
+		return code
+	}
+
+	fetcher, err := NewTMDBFetcher(*configPath, *outputDir)
+	if err != nil {
+		return failed(err)
+	}
+
+	for _, key := range keys {
+		if action == "convert" {
+			err = fetcher.convertToOverlay(key.mediaType, key.mediaID)
+		} else {
+			err = fetcher.expandOverlay(key.mediaType, key.mediaID, *destDir)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "\n错误: %s: %v\n", key, err)
+			code = exitError
+		}
+	}
+	return code
+}
+
+// cmdBatch batch子命令: 从列表文件或标准输入批量获取数据
+// 每行一条记录，如 "movie 842675"、"tv/95480"、TMDB链接或 "tt1234567" 等外部ID
+func cmdBatch(args []string) int {
+	fs := newFlagSet("batch")
+	configPath := fs.String("config", defaultConfigPath, "配置文件路径")
+	outputDir := fs.String("output", defaultOutputDir, "元数据保存目录")
+	workers := fs.Int("workers", 0, "并发数 (默认: 配置文件中的 workers)")
+	rps := fs.Float64("rps", 0, "每秒最多请求数 (默认: 配置文件中的 requests_per_second)")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return parseError(err)
+	}
+
+	path := "-"
+	switch len(positional) {
+	case 0:
+	case 1:
+		path = positional[0]
+	default:
+		return usageError("batch 只接受一个列表文件")
+	}
+
+	fetcher, err := NewTMDBFetcher(*configPath, *outputDir)
+	if err != nil {
+		return failed(err)
+	}
+
+	if *workers > 0 {
+		fetcher.config.Workers = *workers
+	}
+	if *rps > 0 {
+		fetcher.config.RequestsPerSecond = *rps
+		fetcher.limiter = newRateLimiter(*rps)
+	}
+
+	failures, err := fetcher.runBatchFile(path)
+	if err != nil {
+		return failed(err)
+	}
+	if failures > 0 {
+		return exitError
+	}
+	return exitOK
+}
+
+// cmdLint lint子命令: 检查数据目录，存在错误时返回非零退出码
+func cmdLint(args []string) int {
+	fs := newFlagSet("lint")
+	outputDir := fs.String("output", defaultOutputDir, "元数据保存目录")
+	format := fs.String("format", "text", "输出格式: text 或 json")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return parseError(err)
+	}
+	if len(positional) > 0 {
+		return usageError("lint 不接受位置参数: %s", strings.Join(positional, " "))
+	}
+	if *format != "text" && *format != "json" {
+		return usageError("无效的输出格式 '%s'，可选: text, json", *format)
+	}
+
+	issues, err := lintTree(*outputDir)
+	if err != nil {
+		return failed(err)
+	}
+	errorCount, err := printLintIssues(os.Stdout, issues, *format)
+	if err != nil {
+		return failed(err)
+	}
+	if errorCount > 0 {
+		return exitError
+	}
+	return exitOK
+}
+
+// cmdPack pack子命令: 生成内容相同时完全一致的发布包
+func cmdPack(args []string) int {
+	fs := newFlagSet("pack")
+	outputDir := fs.String("output", defaultOutputDir, "元数据保存目录")
+	destDir := fs.String("dest", defaultPackDir, "发布包的输出目录")
+	version := fs.String("version", releaseVersion(time.Now()), "写入 manifest.json 的版本号")
+	repoDir := fs.String("repo", defaultRepoDir, "项目根目录，为空时不查找最后修改的提交")
+	basePath := fs.String("base", "", "上一个版本的 manifest.json，指定时同时生成增量包")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return parseError(err)
+	}
+	if len(positional) > 0 {
+		return usageError("pack 不接受位置参数: %s", strings.Join(positional, " "))
+	}
+
+	// 只读取本地文件，不需要API Key
+	local := &TMDBFetcher{outputDir: *outputDir}
+	manifest, err := local.packRelease(*destDir, *version, *repoDir, *basePath)
+	if err != nil {
+		return failed(err)
+	}
+	fmt.Printf("\n✓ 已生成 %s\n", filepath.Join(*destDir, manifest.Archive))
+	fmt.Printf("  版本: %s\n", manifest.Version)
+	fmt.Printf("  条目数: 电影 %d, 电视剧 %d\n", manifest.Counts["movie"], manifest.Counts["tv"])
+	fmt.Printf("  文件数: %d\n", manifest.Files)
+	fmt.Printf("  大小: %d 字节\n", manifest.Size)
+	fmt.Printf("  SHA256: %s\n", manifest.SHA256)
+	if manifest.Delta != nil {
+		fmt.Printf("\n✓ 已生成增量包 %s\n", filepath.Join(*destDir, manifest.Delta.Archive))
+		fmt.Printf("  基于版本: %s\n", manifest.Delta.From)
+		fmt.Printf("  新增或修改: %d 个文件, 删除: %d 个文件\n", manifest.Delta.Changed, manifest.Delta.Deleted)
+		fmt.Printf("  大小: %d 字节\n", manifest.Delta.Size)
+	}
+	return exitOK
+}
+
+// cmdKeygen keygen子命令: 生成签名用的密钥对
+func cmdKeygen(args []string) int {
+	fs := newFlagSet("keygen")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return parseError(err)
+	}
+	if len(positional) != 1 {
+		return usageError("keygen 需要私钥文件路径，例如: keygen release.key")
+	}
+
+	pub, err := generateSigningKey(positional[0])
+	if err != nil {
+		return failed(err)
+	}
+	fmt.Printf("✓ 私钥: %s (请妥善保管，不要提交到仓库)\n", positional[0])
+	fmt.Printf("  公钥: %s%s\n", positional[0], publicKeyExt)
+	fmt.Printf("  公钥内容: %s\n", base64.StdEncoding.EncodeToString(pub))
+	fmt.Printf("  指纹: %s\n", updater.Fingerprint(pub))
+	fmt.Printf("\n发布流程中请将私钥文件的内容保存为仓库的 %s secret，并公开公钥供下载者校验\n", signingKeyEnv)
+	return exitOK
+}
+
+// cmdSign sign子命令: 对 pack 生成的发布包签名
+func cmdSign(args []string) int {
+	fs := newFlagSet("sign")
+	keyPath := fs.String("key", "", "私钥文件 (默认: 读取环境变量 TMDB_SIGNING_KEY)")
+	destDir := fs.String("dest", defaultPackDir, "发布包所在目录")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return parseError(err)
+	}
+	if len(positional) > 0 {
+		return usageError("sign 不接受位置参数: %s", strings.Join(positional, " "))
+	}
+
+	priv, err := loadPrivateKey(*keyPath)
+	if err != nil {
+		return failed(err)
+	}
+	fingerprint, err := signRelease(*destDir, priv)
+	if err != nil {
+		return failed(err)
+	}
+	fmt.Printf("\n✓ 签名完成，公钥指纹: %s\n", fingerprint)
+	return exitOK
+}
+
+// cmdVerify verify子命令: 离线校验发布包的签名和内容
+func cmdVerify(args []string) int {
+	fs := newFlagSet("verify")
+	pubkey := fs.String("pubkey", "", "base64 编码的公钥或公钥文件路径")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return parseError(err)
+	}
+	archivePath := filepath.Join(defaultPackDir, packArchiveName)
+	switch len(positional) {
+	case 0:
+	case 1:
+		archivePath = positional[0]
+	default:
+		return usageError("verify 只接受一个压缩包路径")
+	}
+	if *pubkey == "" {
+		return usageError("verify 需要 --pubkey 指定公钥")
+	}
+
+	pub, err := parsePublicKey(*pubkey)
+	if err != nil {
+		return usageError("%v", err)
+	}
+	manifest, err := verifyRelease(archivePath, pub)
+	if err != nil {
+		return failed(err)
+	}
+	fmt.Printf("✓ %s 的签名有效\n", archivePath)
+	if manifest == nil {
+		fmt.Printf("  同目录中没有 %s，未校验每个文件\n", updater.ManifestName)
+		return exitOK
+	}
+	fmt.Printf("  版本: %s\n", manifest.Version)
+	if manifest.IsDeltaArchive(archivePath) {
+		fmt.Printf("  增量包基于版本 %s，%d 个文件与 %s 一致\n", manifest.Delta.From, manifest.Delta.Changed, updater.ManifestName)
+		return exitOK
+	}
+	fmt.Printf("  %d 个文件与 %s 一致\n", manifest.Files, updater.ManifestName)
+	return exitOK
+}
+
+// cmdApplyDelta apply-delta子命令: 把增量包应用到上一个版本的数据目录
+func cmdApplyDelta(args []string) int {
+	fs := newFlagSet("apply-delta")
+	targetDir := fs.String("target", "", "上一个版本解压出的 tmdb_config 目录")
+	manifestPath := fs.String("manifest", "", "新版本的 manifest.json (默认: 增量包所在目录中的 manifest.json)")
+	pubkey := fs.String("pubkey", "", "base64 编码的公钥或公钥文件路径，指定时同时校验签名")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return parseError(err)
+	}
+	if len(positional) != 1 {
+		return usageError("apply-delta 需要一个增量包路径，例如: apply-delta tmdb_config.delta.tar.gz --target ./tmdb_config")
+	}
+	if *targetDir == "" {
+		return usageError("apply-delta 需要 --target 指定要更新的目录")
+	}
+	deltaPath := positional[0]
+	if *manifestPath == "" {
+		*manifestPath = filepath.Join(filepath.Dir(deltaPath), updater.ManifestName)
+	}
+
+	var pub ed25519.PublicKey
+	if *pubkey != "" {
+		if pub, err = parsePublicKey(*pubkey); err != nil {
+			return usageError("%v", err)
+		}
+	}
+	manifest, index, err := applyDelta(deltaPath, *manifestPath, *targetDir, pub)
+	if err != nil {
+		return failed(err)
+	}
+	fmt.Printf("✓ %s 已从版本 %s 更新到 %s\n", *targetDir, index.From, manifest.Version)
+	fmt.Printf("  新增或修改: %d 个文件, 删除: %d 个文件\n", len(index.Changed), len(index.Deleted))
+	fmt.Printf("  %d 个文件与 %s 一致\n", manifest.Files, updater.ManifestName)
+	return exitOK
+}
+
+// cmdServe serve子命令: 以TMDB API的格式在本地提供数据，供媒体服务器测试使用
+func cmdServe(args []string) int {
+	fs := newFlagSet("serve")
+	outputDir := fs.String("output", defaultOutputDir, "元数据保存目录")
+	addr := fs.String("addr", defaultServeAddr, "监听地址")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return parseError(err)
+	}
+	if len(positional) > 0 {
+		return usageError("serve 不接受位置参数: %s", strings.Join(positional, " "))
+	}
+
+	if err := serveConfig(*outputDir, *addr); err != nil {
+		return failed(err)
+	}
+	return exitOK
+}
+
+// cmdProxy proxy子命令: 转发请求到TMDB，并合并维护的修改
+func cmdProxy(args []string) int {
+	fs := newFlagSet("proxy")
+	configPath := fs.String("config", defaultConfigPath, "配置文件路径")
+	outputDir := fs.String("output", defaultOutputDir, "元数据保存目录")
+	addr := fs.String("addr", defaultServeAddr, "监听地址")
+	cacheDir := fs.String("cache-dir", defaultCacheDir, "TMDB响应的缓存目录，为空时不缓存")
+	cacheTTL := fs.Duration("cache-ttl", defaultCacheTTL, "缓存有效期")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return parseError(err)
+	}
+	if len(positional) > 0 {
+		return usageError("proxy 不接受位置参数: %s", strings.Join(positional, " "))
+	}
+	if *cacheTTL <= 0 {
+		return usageError("--cache-ttl 必须大于 0，不需要缓存时请使用 --cache-dir \"\"")
+	}
+
+	fetcher, err := NewTMDBFetcher(*configPath, *outputDir)
+	if err != nil {
+		return failed(err)
+	}
+	if err := fetcher.serveProxy(*addr, *cacheDir, *cacheTTL); err != nil {
+		return failed(err)
+	}
+	return exitOK
+}
+
+// cmdWatch watch子命令: 报告维护的条目在TMDB上的修改，有修改时返回 exitConflict 提醒核对
+func cmdWatch(args []string) int {
+	fs := newFlagSet("watch")
+	configPath := fs.String("config", defaultConfigPath, "配置文件路径")
+	outputDir := fs.String("output", defaultOutputDir, "元数据保存目录")
+	statePath := fs.String("state", defaultWatchStatePath, "状态文件路径")
+	days := fs.Int("days", defaultWatchDays, "首次检查的条目回溯的天数")
+	allLanguages := fs.Bool("all-languages", false, "报告全部语言的修改")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return parseError(err)
+	}
+	if len(positional) > 0 {
+		return usageError("watch 不接受位置参数: %s", strings.Join(positional, " "))
+	}
+	if *days <= 0 {
+		return usageError("--days 必须大于 0")
+	}
+
+	fetcher, err := NewTMDBFetcher(*configPath, *outputDir)
+	if err != nil {
+		return failed(err)
+	}
+
+	reports, failures, err := fetcher.watchMedia(*statePath, *days, *allLanguages)
+	if err != nil {
+		return failed(err)
+	}
+	printWatchReports(os.Stdout, reports)
+	switch {
+	case failures > 0:
+		fmt.Fprintf(os.Stderr, "\n%d 个条目检查失败，下次运行时会重新检查\n", failures)
+		return exitError
+	case len(reports) > 0:
+		return exitConflict
+	}
+	return exitOK
+}
+
+// cmdSync sync子命令: 从主库同步最新代码
+func cmdSync(args []string) int {
+	fs := newFlagSet("sync")
+	repoDir := fs.String("repo", defaultRepoDir, "项目根目录")
+	assumeYes := fs.Bool("yes", false, "对所有确认提示自动回答 y")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return parseError(err)
+	}
+	if len(positional) > 0 {
+		return usageError("sync 不接受位置参数: %s", strings.Join(positional, " "))
+	}
+
+	if err := syncFromMainRepo(bufio.NewReader(os.Stdin), *repoDir, *assumeYes); err != nil {
+		return failed(err)
+	}
+	return exitOK
+}
+
+// cmdSubmit submit子命令: 提交 tmdb_config 的修改到PR
+func cmdSubmit(args []string) int {
+	fs := newFlagSet("submit")
+	repoDir := fs.String("repo", defaultRepoDir, "项目根目录")
+	branch := fs.String("branch", "", "新建分支名称 (默认: 自动生成)")
+	existing := fs.Bool("existing", false, "推送到当前分支（提交到已有的PR）")
+	message := fs.String("message", "Update TMDB config metadata", "提交信息")
+	open := fs.Bool("open", false, "完成后在浏览器中打开PR链接")
+	assumeYes := fs.Bool("yes", false, "对所有确认提示自动回答 y")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return parseError(err)
+	}
+	if len(positional) > 0 {
+		return usageError("submit 不接受位置参数: %s", strings.Join(positional, " "))
+	}
+	if *existing && *branch != "" {
+		return usageError("--branch 与 --existing 不能同时使用")
+	}
+	if strings.TrimSpace(*message) == "" {
+		return usageError("--message 不能为空")
+	}
+
+	absRepoDir, err := resolveGitRepo(*repoDir)
+	if err != nil {
+		return failed(err)
+	}
+
+	changes := pendingChanges(absRepoDir)
+	if len(changes) == 0 {
+		fmt.Println("✓ 当前没有需要提交的更改")
+		return exitOK
+	}
+	fmt.Println("检测到以下更改:")
+	fmt.Println(changes)
+
+	if !confirm(bufio.NewReader(os.Stdin), "确认提交这些更改? (y/n): ", *assumeYes) {
+		fmt.Println("已取消")
+		return exitError
+	}
+
+	opts := submitOptions{
+		repoDir: absRepoDir,
+		message: *message,
+	}
+	if *existing {
+		opts.branchName, err = currentBranch(absRepoDir)
+		if err != nil {
+			return failed(err)
+		}
+		if opts.branchName == "main" || opts.branchName == "master" {
+			return failed(fmt.Errorf("不能在main/master分支上提交，请先创建新分支"))
+		}
+	} else {
+		opts.newBranch = true
+		if *branch == "" {
+			opts.branchName = nextAvailableBranch(absRepoDir, "update-tmdb-config")
+		} else if branchExists(absRepoDir, *branch) {
+			return failed(fmt.Errorf("分支 '%s' 已存在，请使用其他分支名称或 --existing", *branch))
+		} else {
+			opts.branchName = *branch
+		}
+	}
+
+	prURL, err := commitAndPush(opts)
+	if err != nil {
+		return failed(err)
+	}
+
+	if *open {
+		if err := openBrowser(prURL); err != nil {
+			fmt.Printf("打开浏览器失败: %v\n", err)
+		}
+	}
+	return exitOK
+}
+
+// cmdExport export子命令: 把维护的数据导出为其他格式
+func cmdExport(args []string) int {
+	if len(args) == 0 {
+		return usageError("export 需要子命令 nfo 或 sqlite，例如: export nfo")
+	}
+	switch args[0] {
+	case "nfo":
+		return cmdExportNFO(args[1:])
+	case "sqlite":
+		return cmdExportSQLite(args[1:])
+	}
+	return usageError("未知的 export 子命令 '%s'，可选: nfo, sqlite", args[0])
+}
+
+// cmdExportNFO export nfo子命令: 导出 Kodi/Jellyfin 使用的 NFO 文件
+func cmdExportNFO(args []string) int {
+	fs := newFlagSet("export nfo")
+	outputDir := fs.String("output", defaultOutputDir, "元数据保存目录")
+	destDir := fs.String("dest", defaultNFODir, "NFO 文件的输出目录")
+	country := fs.String("country", defaultNFOCountry, "分级使用的地区")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return parseError(err)
+	}
+	keys, code := parseLocalKeys("export nfo", positional)
+	if code != exitOK {
+		return code
+	}
+
+	total, failures, err := exportNFO(*outputDir, *destDir, strings.ToUpper(*country), keys)
+	if err != nil {
+		return failed(err)
+	}
+	for _, failure := range failures {
+		fmt.Fprintf(os.Stderr, "\n错误: %s\n", failure)
+	}
+	fmt.Printf("\n✓ 共导出 %d 个 NFO 文件到 %s\n", total, *destDir)
+	if len(failures) > 0 {
+		return exitError
+	}
+	return exitOK
+}
+
+// cmdExportSQLite export sqlite子命令: 把全部条目导出为SQLite数据库
+func cmdExportSQLite(args []string) int {
+	fs := newFlagSet("export sqlite")
+	outputDir := fs.String("output", defaultOutputDir, "元数据保存目录")
+	dbPath := fs.String("dest", defaultSQLitePath, "数据库文件")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return parseError(err)
+	}
+	if len(positional) > 0 {
+		return usageError("export sqlite 不接受位置参数: %s", strings.Join(positional, " "))
+	}
+
+	counts, err := exportSQLite(*outputDir, *dbPath)
+	if err != nil {
+		return failed(err)
+	}
+	fmt.Printf("\n✓ 已导出 %d 部电影、%d 部电视剧到 %s\n", counts["movie"], counts["tv"], *dbPath)
+	return exitOK
+}
+
+// parseLocalKeys 解析可选的 "<movie|tv> <id>..." 位置参数，只接受数字ID，没有位置参数时返回 nil
+func parseLocalKeys(command string, positional []string) ([]mediaKey, int) {
+	if len(positional) == 0 {
+		return nil, exitOK
+	}
+	mediaType := strings.ToLower(positional[0])
+	if mediaType != "movie" && mediaType != "tv" {
+		return nil, usageError("无效的媒体类型 '%s'，可选: movie, tv", positional[0])
+	}
+	if len(positional) < 2 {
+		return nil, usageError("%s 需要至少一个ID，例如: %s %s 842675", command, command, mediaType)
+	}
+	var keys []mediaKey
+	for _, id := range positional[1:] {
+		if !isNumericID(id) {
+			return nil, usageError("无效的ID '%s'", id)
+		}
+		keys = append(keys, mediaKey{mediaType: mediaType, mediaID: id})
+	}
+	return keys, exitOK
+}
+
+// defaultUpdateDir update 命令默认的安装目录，相对于 scripts/cli 目录
+const defaultUpdateDir = "../tmdb_release"
+
+// cmdUpdate update子命令: 从发布地址更新本地安装的数据
+func cmdUpdate(args []string) int {
+	fs := newFlagSet("update")
+	feedURL := fs.String("feed", updater.DefaultFeedURL, "发布地址，其下有 manifest.json 和压缩包")
+	dir := fs.String("dir", defaultUpdateDir, "安装目录")
+	pubkey := fs.String("pubkey", "", "base64 编码的公钥或公钥文件路径，指定时同时校验签名")
+	checkOnly := fs.Bool("check", false, "只检查是否有新版本，不下载")
+	rollback := fs.Bool("rollback", false, "回滚到上一个版本")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return parseError(err)
+	}
+	if len(positional) > 0 {
+		return usageError("update 不接受位置参数: %s", strings.Join(positional, " "))
+	}
+	if *checkOnly && *rollback {
+		return usageError("--check 和 --rollback 不能同时使用")
+	}
+
+	u := updater.New(*feedURL, *dir)
+	u.Logf = func(format string, a ...interface{}) { fmt.Printf(format+"\n", a...) }
+	if *pubkey != "" {
+		if u.PublicKey, err = parsePublicKey(*pubkey); err != nil {
+			return usageError("%v", err)
+		}
+	}
+
+	if *rollback {
+		previous, err := u.Rollback()
+		if err != nil {
+			return failed(err)
+		}
+		fmt.Printf("✓ 已回滚到版本 %s: %s\n", previous.Version, u.ConfigDir())
+		return exitOK
+	}
+
+	if *checkOnly {
+		installed, err := u.Installed()
+		if err != nil {
+			return failed(err)
+		}
+		latest, err := u.Latest()
+		if err != nil {
+			return failed(err)
+		}
+		switch {
+		case installed == nil:
+			fmt.Printf("尚未安装，最新版本: %s\n", latest.Version)
+		case installed.Version == latest.Version:
+			fmt.Printf("已是最新版本: %s\n", installed.Version)
+		default:
+			fmt.Printf("有新版本: %s → %s\n", installed.Version, latest.Version)
+		}
+		return exitOK
+	}
+
+	result, err := u.Update()
+	if err != nil {
+		return failed(err)
+	}
+	if !result.Updated {
+		fmt.Printf("已是最新版本: %s\n", result.To)
+		return exitOK
+	}
+	from := result.From
+	if from == "" {
+		from = "未安装"
+	}
+	method := "完整的压缩包"
+	if result.Delta {
+		method = "增量包"
+	}
+	fmt.Printf("\n✓ 已更新: %s → %s (使用%s)\n", from, result.To, method)
+	fmt.Printf("  数据目录: %s\n", u.ConfigDir())
+	return exitOK
+}
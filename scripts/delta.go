@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"tmdb-manager/updater"
+)
+
+// deltaArchiveName 增量包的文件名，增量包顶层目录中的文件列表为 updater.DeltaIndexName
+const deltaArchiveName = "tmdb_config.delta.tar.gz"
+
+// encodeDeltaArchive 与上一个版本的 manifest 比较，生成只包含新增或修改的文件以及 delta.json 的增量包
+func encodeDeltaArchive(base *updater.Manifest, version string, files []packFile) ([]byte, *updater.Delta, error) {
+	previous := base.FileHashes()
+	index := updater.DeltaIndex{From: base.Version, To: version, Changed: []string{}, Deleted: []string{}}
+
+	var changed []packFile
+	current := make(map[string]bool, len(files))
+	for _, file := range files {
+		current[file.relPath] = true
+		sum := sha256.Sum256(file.data)
+		if previous[file.relPath] != hex.EncodeToString(sum[:]) {
+			changed = append(changed, file)
+			index.Changed = append(index.Changed, file.relPath)
+		}
+	}
+	for relPath := range previous {
+		if !current[relPath] {
+			index.Deleted = append(index.Deleted, relPath)
+		}
+	}
+	sort.Strings(index.Deleted)
+
+	encoded, err := encodeJSONFile(index)
+	if err != nil {
+		return nil, nil, err
+	}
+	var archive bytes.Buffer
+	if err := writePackArchive(&archive, changed, packFile{relPath: updater.DeltaIndexName, data: encoded}); err != nil {
+		return nil, nil, fmt.Errorf("生成增量包失败: %v", err)
+	}
+
+	sum := sha256.Sum256(archive.Bytes())
+	delta := &updater.Delta{
+		Archive: deltaArchiveName,
+		From:    base.Version,
+		Size:    int64(archive.Len()),
+		SHA256:  hex.EncodeToString(sum[:]),
+		Changed: len(index.Changed),
+		Deleted: len(index.Deleted),
+	}
+	return archive.Bytes(), delta, nil
+}
+
+// applyDelta 把增量包应用到上一个版本解压出的 tmdb_config 目录，pub 不为 nil 时先校验增量包和 manifest.json 的签名
+// 在暂存目录中应用并按新版本的 manifest 校验全部文件，一致后才替换目标目录，失败时目标目录不会改动
+func applyDelta(deltaPath, manifestPath, targetDir string, pub ed25519.PublicKey) (*updater.Manifest, *updater.DeltaIndex, error) {
+	archive, err := os.ReadFile(deltaPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取增量包失败: %v", err)
+	}
+	manifestData, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取 %s 失败: %v", updater.ManifestName, err)
+	}
+	if pub != nil {
+		if err := checkSignature(pub, deltaPath, archive); err != nil {
+			return nil, nil, err
+		}
+		if err := checkSignature(pub, manifestPath, manifestData); err != nil {
+			return nil, nil, err
+		}
+	}
+	manifest, err := updater.ParseManifest(manifestData)
+	if err != nil {
+		return nil, nil, err
+	}
+	if pub != nil && manifest.SigningKey != updater.Fingerprint(pub) {
+		return nil, nil, fmt.Errorf("%s 中的公钥指纹 %s 与使用的公钥 %s 不一致", updater.ManifestName, manifest.SigningKey, updater.Fingerprint(pub))
+	}
+	if !checkDirectoryExists(targetDir) {
+		return nil, nil, fmt.Errorf("目录不存在: %s", targetDir)
+	}
+
+	absTarget, err := filepath.Abs(targetDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	stagingDir, err := os.MkdirTemp(filepath.Dir(absTarget), "."+filepath.Base(absTarget)+"-delta-*")
+	if err != nil {
+		return nil, nil, fmt.Errorf("创建暂存目录失败: %v", err)
+	}
+	defer os.RemoveAll(stagingDir) // 替换成功后为空操作
+
+	if err := updater.CopyDir(absTarget, stagingDir); err != nil {
+		return nil, nil, err
+	}
+	index, err := updater.ApplyDelta(stagingDir, archive, manifest)
+	if err != nil {
+		return nil, nil, fmt.Errorf("目标目录 %s 未做任何改动: %v", targetDir, err)
+	}
+	if err := replaceWithStagingDir(stagingDir, absTarget); err != nil {
+		return nil, nil, err
+	}
+	return manifest, index, nil
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tmdb-manager/updater"
+)
+
+// writeFiles 按相对路径写入测试文件
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for relPath, content := range files {
+		filePath := filepath.Join(dir, filepath.FromSlash(relPath))
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// extractPack 把发布包中的 tmdb_config 文件解压到 dir
+func extractPack(t *testing.T, archivePath, dir string) {
+	t.Helper()
+	archive, err := os.ReadFile(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, _, err := updater.ReadArchive(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		writeFiles(t, dir, map[string]string{file.Path: string(file.Data)})
+	}
+}
+
+// testRelease 测试用的两个版本: v1 为完整发布包，v2 同时带有相对 v1 的增量包，均已签名
+type testRelease struct {
+	v1Dir, v2Dir string
+	pub          ed25519.PublicKey
+}
+
+func newTestRelease(t *testing.T) *testRelease {
+	t.Helper()
+	root := t.TempDir()
+	configDir := filepath.Join(root, "tmdb_config")
+	writeFiles(t, configDir, map[string]string{
+		"movie/1/details.json": `{"id": 1, "title": "A"}`,
+		"movie/1/credits.json": `{"cast": []}`,
+		"tv/2/details.json":    `{"id": 2, "name": "B"}`,
+		".staging/x/tmp.json":  `{}`,
+	})
+	f := &TMDBFetcher{outputDir: configDir}
+	r := &testRelease{v1Dir: filepath.Join(root, "v1"), v2Dir: filepath.Join(root, "v2")}
+	if _, err := f.packRelease(r.v1Dir, "v1", "", ""); err != nil {
+		t.Fatalf("生成 v1 失败: %v", err)
+	}
+
+	writeFiles(t, configDir, map[string]string{
+		"movie/1/details.json": `{"id": 1, "title": "A2"}`,
+		"movie/3/details.json": `{"id": 3, "title": "C"}`,
+	})
+	if err := os.RemoveAll(filepath.Join(configDir, "tv")); err != nil {
+		t.Fatal(err)
+	}
+	manifest, err := f.packRelease(r.v2Dir, "v2", "", filepath.Join(r.v1Dir, updater.ManifestName))
+	if err != nil {
+		t.Fatalf("生成 v2 失败: %v", err)
+	}
+	if manifest.Delta == nil || manifest.Delta.Changed != 2 || manifest.Delta.Deleted != 1 {
+		t.Fatalf("增量包记录 = %+v, 期望修改 2 个、删除 1 个文件", manifest.Delta)
+	}
+
+	keyPath := filepath.Join(root, "signing.key")
+	if r.pub, err = generateSigningKey(keyPath); err != nil {
+		t.Fatal(err)
+	}
+	priv, err := loadPrivateKey(keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range []string{r.v1Dir, r.v2Dir} {
+		if _, err := signRelease(dir, priv); err != nil {
+			t.Fatalf("签名 %s 失败: %v", dir, err)
+		}
+	}
+	return r
+}
+
+func TestPackSignVerifyApply(t *testing.T) {
+	r := newTestRelease(t)
+
+	for _, archivePath := range []string{
+		filepath.Join(r.v1Dir, packArchiveName),
+		filepath.Join(r.v2Dir, packArchiveName),
+		filepath.Join(r.v2Dir, deltaArchiveName),
+	} {
+		if _, err := verifyRelease(archivePath, r.pub); err != nil {
+			t.Errorf("校验 %s 失败: %v", archivePath, err)
+		}
+	}
+
+	targetDir := filepath.Join(t.TempDir(), "tmdb_config")
+	extractPack(t, filepath.Join(r.v1Dir, packArchiveName), targetDir)
+	if _, err := os.Stat(filepath.Join(targetDir, ".staging")); !os.IsNotExist(err) {
+		t.Error("发布包中不应包含隐藏目录")
+	}
+	manifest, index, err := applyDelta(filepath.Join(r.v2Dir, deltaArchiveName), filepath.Join(r.v2Dir, updater.ManifestName), targetDir, r.pub)
+	if err != nil {
+		t.Fatalf("应用增量包失败: %v", err)
+	}
+	if index.From != "v1" || index.To != "v2" || len(index.Deleted) != 1 || index.Deleted[0] != "tv/2/details.json" {
+		t.Errorf("delta.json = %+v", index)
+	}
+	if err := updater.VerifyTree(targetDir, manifest); err != nil {
+		t.Errorf("应用增量包后与 v2 不一致: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(targetDir, "tv")); !os.IsNotExist(err) {
+		t.Error("删除文件后为空的目录应一并删除")
+	}
+}
+
+func TestVerifyReleaseRejects(t *testing.T) {
+	otherPub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		archive string
+		tamper  func(t *testing.T, r *testRelease, dir string)
+		pub     func(r *testRelease) ed25519.PublicKey
+		wantErr string
+	}{
+		{
+			name:    "压缩包被修改",
+			archive: packArchiveName,
+			tamper:  func(t *testing.T, r *testRelease, dir string) { flipLastByte(t, filepath.Join(dir, packArchiveName)) },
+			wantErr: "签名",
+		},
+		{
+			name:    "增量包被修改",
+			archive: deltaArchiveName,
+			tamper:  func(t *testing.T, r *testRelease, dir string) { flipLastByte(t, filepath.Join(dir, deltaArchiveName)) },
+			wantErr: "签名",
+		},
+		{
+			name:    "manifest.json 被修改",
+			archive: packArchiveName,
+			tamper: func(t *testing.T, r *testRelease, dir string) {
+				manifestPath := filepath.Join(dir, updater.ManifestName)
+				data, err := os.ReadFile(manifestPath)
+				if err != nil {
+					t.Fatal(err)
+				}
+				writeFiles(t, dir, map[string]string{updater.ManifestName: strings.Replace(string(data), `"v2"`, `"v9"`, 1)})
+			},
+			wantErr: "签名",
+		},
+		{
+			name:    "缺少签名文件",
+			archive: packArchiveName,
+			tamper: func(t *testing.T, r *testRelease, dir string) {
+				if err := os.Remove(filepath.Join(dir, packArchiveName+updater.SignatureExt)); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: "读取签名失败",
+		},
+		{
+			name:    "签名正确但与 manifest.json 不一致的压缩包",
+			archive: packArchiveName,
+			tamper: func(t *testing.T, r *testRelease, dir string) {
+				archivePath := filepath.Join(dir, packArchiveName)
+				var archive bytes.Buffer
+				if err := writePackArchive(&archive, []packFile{{relPath: "movie/1/details.json", data: []byte(`{}`)}}); err != nil {
+					t.Fatal(err)
+				}
+				writeFiles(t, dir, map[string]string{packArchiveName: archive.String()})
+				priv, err := loadPrivateKey(filepath.Join(filepath.Dir(r.v2Dir), "signing.key"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := writeSignature(priv, archivePath, archive.Bytes()); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: "中的记录不一致",
+		},
+		{
+			name:    "使用其他公钥",
+			archive: packArchiveName,
+			pub:     func(r *testRelease) ed25519.PublicKey { return otherPub },
+			wantErr: "签名",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRelease(t)
+			if tt.tamper != nil {
+				tt.tamper(t, r, r.v2Dir)
+			}
+			pub := r.pub
+			if tt.pub != nil {
+				pub = tt.pub(r)
+			}
+			_, err := verifyRelease(filepath.Join(r.v2Dir, tt.archive), pub)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("错误 = %v, 期望包含 %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// flipLastByte 修改文件的最后一个字节
+func flipLastByte(t *testing.T, filePath string) {
+	t.Helper()
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)-1] ^= 0xff
+	if err := os.WriteFile(filePath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestApplyDeltaRejectsUnsafeDeletedPath(t *testing.T) {
+	r := newTestRelease(t)
+	root := t.TempDir()
+	targetDir := filepath.Join(root, "tmdb_config")
+	extractPack(t, filepath.Join(r.v1Dir, packArchiveName), targetDir)
+	writeFiles(t, root, map[string]string{"victim.json": `{}`})
+
+	index, err := encodeJSONFile(updater.DeltaIndex{From: "v1", To: "v2", Changed: []string{}, Deleted: []string{"../victim.json"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var archive bytes.Buffer
+	if err := writePackArchive(&archive, nil, packFile{relPath: updater.DeltaIndexName, data: index}); err != nil {
+		t.Fatal(err)
+	}
+	manifest, err := updater.LoadManifest(filepath.Join(r.v2Dir, updater.ManifestName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(archive.Bytes())
+	manifest.Delta = &updater.Delta{Archive: deltaArchiveName, From: "v1", Size: int64(archive.Len()), SHA256: hex.EncodeToString(sum[:]), Deleted: 1}
+	releaseDir := t.TempDir()
+	writeFiles(t, releaseDir, map[string]string{deltaArchiveName: archive.String()})
+	if err := saveJSON(manifest, filepath.Join(releaseDir, updater.ManifestName)); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = applyDelta(filepath.Join(releaseDir, deltaArchiveName), filepath.Join(releaseDir, updater.ManifestName), targetDir, nil)
+	if err == nil || !strings.Contains(err.Error(), "路径不正确") {
+		t.Errorf("错误 = %v, 期望拒绝 ../ 路径", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "victim.json")); err != nil {
+		t.Errorf("目标目录之外的文件被删除: %v", err)
+	}
+}
+
+func TestApplyDeltaLeavesMismatchedTargetUnchanged(t *testing.T) {
+	r := newTestRelease(t)
+	targetDir := filepath.Join(t.TempDir(), "tmdb_config")
+	extractPack(t, filepath.Join(r.v1Dir, packArchiveName), targetDir)
+	writeFiles(t, targetDir, map[string]string{"movie/1/credits.json": `{"cast": [{"id": 9}]}`})
+
+	_, _, err := applyDelta(filepath.Join(r.v2Dir, deltaArchiveName), filepath.Join(r.v2Dir, updater.ManifestName), targetDir, r.pub)
+	if err == nil || !strings.Contains(err.Error(), "未做任何改动") {
+		t.Fatalf("错误 = %v, 期望数据不一致", err)
+	}
+	data, err := os.ReadFile(filepath.Join(targetDir, "movie/1/details.json"))
+	if err != nil || string(data) != `{"id": 1, "title": "A"}` {
+		t.Errorf("目标目录被修改: %s, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(targetDir, "tv/2/details.json")); err != nil {
+		t.Errorf("目标目录被修改: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(targetDir, "movie/3")); !os.IsNotExist(err) {
+		t.Error("目标目录被修改: 出现了 movie/3")
+	}
+}
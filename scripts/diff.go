@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// fieldDiff 快照与本地数据之间单个字段的差异
+type fieldDiff struct {
+	path   string
+	kind   string // "+" 本地新增, "-" 本地删除, "~" 本地修改
+	before interface{}
+	after  interface{}
+}
+
+// diffJSON 递归比较两个JSON值，记录字段级差异
+// 数组元素能按识别字段 (id、iso_3166_1 等) 对应时按元素比较，否则按下标比较
+func diffJSON(path string, before, after interface{}, diffs *[]fieldDiff) {
+	if reflect.DeepEqual(before, after) {
+		return
+	}
+	if before == absent {
+		*diffs = append(*diffs, fieldDiff{path: path, kind: "+", before: before, after: after})
+		return
+	}
+	if after == absent {
+		*diffs = append(*diffs, fieldDiff{path: path, kind: "-", before: before, after: after})
+		return
+	}
+
+	beforeMap, beforeIsMap := before.(map[string]interface{})
+	afterMap, afterIsMap := after.(map[string]interface{})
+	if beforeIsMap && afterIsMap {
+		keys := make(map[string]bool)
+		for k := range beforeMap {
+			keys[k] = true
+		}
+		for k := range afterMap {
+			keys[k] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+		for _, k := range sorted {
+			diffJSON(joinPath(path, k), lookup(beforeMap, k), lookup(afterMap, k), diffs)
+		}
+		return
+	}
+
+	beforeArr, beforeIsArr := before.([]interface{})
+	afterArr, afterIsArr := after.([]interface{})
+	if beforeIsArr && afterIsArr {
+		diffArrays(path, beforeArr, afterArr, diffs)
+		return
+	}
+
+	*diffs = append(*diffs, fieldDiff{path: path, kind: "~", before: before, after: after})
+}
+
+// diffArrays 比较两个数组
+func diffArrays(path string, before, after []interface{}, diffs *[]fieldDiff) {
+	if fields := arrayKeyFor(before, after); fields != nil {
+		afterIndex := indexByKey(after, fields)
+		beforeIndex := indexByKey(before, fields)
+		for _, b := range before {
+			key := elementKey(b, fields)
+			diffJSON(elementPath(path, fields, key), b, lookupKey(afterIndex, key), diffs)
+		}
+		for _, a := range after {
+			key := elementKey(a, fields)
+			if _, ok := beforeIndex[key]; !ok {
+				diffJSON(elementPath(path, fields, key), absent, a, diffs)
+			}
+		}
+		return
+	}
+
+	n := len(before)
+	if len(after) > n {
+		n = len(after)
+	}
+	for i := 0; i < n; i++ {
+		b, a := absent, absent
+		if i < len(before) {
+			b = before[i]
+		}
+		if i < len(after) {
+			a = after[i]
+		}
+		diffJSON(path+"["+strconv.Itoa(i)+"]", b, a, diffs)
+	}
+}
+
+// diffSnapshot 比较快照与本地维护的文件，返回差异数量
+// relPaths 为空时比较快照中的全部文件
+func (f *TMDBFetcher) diffSnapshot(mediaType, mediaID string, relPaths []string) (int, error) {
+	meta, err := f.loadSnapshotMeta(mediaType, mediaID)
+	if os.IsNotExist(err) {
+		return 0, fmt.Errorf("没有 %s/%s 的TMDB原始数据快照 (%s)", mediaType, mediaID, f.snapshotDir(mediaType, mediaID))
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	// 使用覆盖文件维护的条目，比较快照与应用覆盖文件后的结果
+	o, err := f.loadOverlay(mediaType, mediaID)
+	if err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+
+	fmt.Printf("快照: %s\n", f.snapshotDir(mediaType, mediaID))
+	fmt.Printf("  获取时间: %s\n", meta.FetchedAt)
+	fmt.Printf("  语言: %s\n", meta.Language)
+
+	if len(relPaths) == 0 {
+		for _, source := range meta.Files {
+			relPaths = append(relPaths, source.File)
+		}
+	}
+
+	total := 0
+	for _, relPath := range relPaths {
+		source := sourceFor(mediaType, mediaID, relPath)
+		fmt.Printf("\n=== %s (%s", relPath, source.Endpoint)
+		if source.AppendToResponse != "" {
+			fmt.Printf("?append_to_response=%s", source.AppendToResponse)
+		}
+		fmt.Println(")")
+
+		snapshot, err := f.loadSnapshotFile(mediaType, mediaID, relPath)
+		if err != nil {
+			return total, err
+		}
+		var before interface{} = absent
+		if snapshot != nil {
+			before = snapshot
+		}
+
+		var after interface{} = absent
+		if o != nil {
+			if patch, ok := o[relPath]; !ok {
+				after = before
+			} else if patch != nil {
+				after = mergePatch(snapshot, patch)
+			}
+		} else {
+			local, err := loadJSON(filepath.Join(f.mediaDir(mediaType, mediaID), filepath.FromSlash(relPath)))
+			if err == nil {
+				after = local
+			} else if !os.IsNotExist(err) {
+				return total, err
+			}
+		}
+
+		var diffs []fieldDiff
+		diffJSON("", before, after, &diffs)
+		if len(diffs) == 0 {
+			fmt.Println("  与快照一致，没有本地修改")
+			continue
+		}
+
+		for _, d := range diffs {
+			path := d.path
+			if path == "" {
+				path = "(整个文件)"
+			}
+			switch d.kind {
+			case "+":
+				fmt.Printf("  + %s: %s\n", path, formatValue(d.after))
+			case "-":
+				fmt.Printf("  - %s: %s\n", path, formatValue(d.before))
+			default:
+				fmt.Printf("  ~ %s: %s → %s\n", path, formatValue(d.before), formatValue(d.after))
+			}
+		}
+		total += len(diffs)
+	}
+
+	fmt.Printf("\n共 %d 处本地修改\n", total)
+	return total, nil
+}
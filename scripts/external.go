@@ -0,0 +1,173 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// externalID 其他站点的ID，如 IMDb 的 tt1234567、TVDB 的 12345
+type externalID struct {
+	source string // TMDB find 接口的 external_source，如 imdb_id
+	id     string
+}
+
+func (e externalID) String() string {
+	return e.source + ":" + e.id
+}
+
+// externalSources 输入前缀与TMDB external_source 的对应关系
+var externalSources = map[string]string{
+	"imdb":      "imdb_id",
+	"tvdb":      "tvdb_id",
+	"wikidata":  "wikidata_id",
+	"facebook":  "facebook_id",
+	"instagram": "instagram_id",
+	"twitter":   "twitter_id",
+	"tiktok":    "tiktok_id",
+	"youtube":   "youtube_id",
+}
+
+var (
+	imdbIDPattern  = regexp.MustCompile(`^tt\d+$`)
+	imdbURLPattern = regexp.MustCompile(`/title/(tt\d+)`)
+)
+
+var errDoubanUnsupported = errors.New("TMDB不支持通过豆瓣ID查找，请改用IMDb ID (豆瓣页面的 \"IMDb:\" 一栏)、TMDB ID或TMDB链接")
+
+// parseExternalID 解析外部ID，支持以下格式:
+//
+//	tt1234567
+//	imdb:tt1234567
+//	tvdb:12345
+//	wikidata:Q123456
+//	https://www.imdb.com/title/tt1234567/
+//
+// 豆瓣ID和豆瓣链接无法通过TMDB查找，会返回说明原因的错误
+// 返回 false 表示输入不是外部ID
+func parseExternalID(input string) (externalID, bool, error) {
+	input = strings.TrimSpace(input)
+	if imdbIDPattern.MatchString(strings.ToLower(input)) {
+		return externalID{source: "imdb_id", id: strings.ToLower(input)}, true, nil
+	}
+	if isSiteURL(input, "imdb.com") {
+		m := imdbURLPattern.FindStringSubmatch(input)
+		if m == nil {
+			return externalID{}, true, fmt.Errorf("无法从IMDb链接中识别ID: %s", input)
+		}
+		return externalID{source: "imdb_id", id: m[1]}, true, nil
+	}
+	if isSiteURL(input, "douban.com") {
+		return externalID{}, true, errDoubanUnsupported
+	}
+
+	idx := strings.Index(input, ":")
+	if idx <= 0 || strings.Contains(input, "://") {
+		return externalID{}, false, nil
+	}
+	prefix, id := strings.ToLower(input[:idx]), strings.TrimSpace(input[idx+1:])
+	if prefix == "douban" {
+		return externalID{}, true, errDoubanUnsupported
+	}
+	source, ok := externalSources[prefix]
+	if !ok {
+		return externalID{}, false, nil
+	}
+	if id == "" {
+		return externalID{}, true, fmt.Errorf("'%s' 缺少ID", input)
+	}
+	if source == "imdb_id" {
+		id = strings.ToLower(id)
+		if !imdbIDPattern.MatchString(id) {
+			return externalID{}, true, fmt.Errorf("无效的IMDb ID '%s'，格式应为 tt1234567", id)
+		}
+	}
+	return externalID{source: source, id: id}, true, nil
+}
+
+// findByExternalID 通过TMDB find 接口把外部ID解析为TMDB条目
+// 结果中同时有电影和电视剧时优先使用 preferType，单集/单季的结果会归到所属的电视剧
+func (f *TMDBFetcher) findByExternalID(ext externalID, preferType string) (string, string, error) {
+	data, err := f.makeRequest("/find/"+ext.id, map[string]string{"external_source": ext.source})
+	if err != nil {
+		return "", "", err
+	}
+
+	candidates := map[string][]string{
+		"movie": findResultIDs(data, "movie_results", "id"),
+		"tv":    findResultIDs(data, "tv_results", "id"),
+	}
+	if len(candidates["tv"]) == 0 {
+		candidates["tv"] = append(findResultIDs(data, "tv_episode_results", "show_id"),
+			findResultIDs(data, "tv_season_results", "show_id")...)
+	}
+
+	if ids := candidates[preferType]; len(ids) > 0 {
+		return preferType, ids[0], nil
+	}
+	switch {
+	case len(candidates["movie"]) > 0 && len(candidates["tv"]) > 0:
+		return "", "", fmt.Errorf("%s 同时对应电影 %s 和电视剧 %s，请指定媒体类型", ext, candidates["movie"][0], candidates["tv"][0])
+	case len(candidates["movie"]) > 0:
+		return "movie", candidates["movie"][0], nil
+	case len(candidates["tv"]) > 0:
+		return "tv", candidates["tv"][0], nil
+	}
+	return "", "", fmt.Errorf("%w: 没有与 %s 对应的电影或电视剧", errNotFound, ext)
+}
+
+// findResultIDs 取出 find 结果列表中每一项的ID字段
+func findResultIDs(data map[string]interface{}, listKey, idKey string) []string {
+	var ids []string
+	items, _ := data[listKey].([]interface{})
+	for _, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if id, ok := m[idKey].(float64); ok {
+			ids = append(ids, fmt.Sprintf("%d", int64(id)))
+		}
+	}
+	return ids
+}
+
+// resolveMediaID 把用户输入的ID或链接解析为TMDB条目
+//   - TMDB数字ID: 使用 preferType 作为媒体类型
+//   - TMDB链接、"tv/95480" 等: 媒体类型取自输入本身
+//   - 外部ID、IMDb链接: 通过 find 接口查找，媒体类型可能与 preferType 不同
+func (f *TMDBFetcher) resolveMediaID(preferType, input string) (string, string, error) {
+	input = strings.TrimSpace(input)
+	if isNumericID(input) {
+		if preferType == "" {
+			return "", "", fmt.Errorf("'%s' 缺少媒体类型，请写成 movie %s 或 tv %s", input, input, input)
+		}
+		return preferType, input, nil
+	}
+	ext, ok, err := parseExternalID(input)
+	if err != nil {
+		return "", "", err
+	}
+	if !ok {
+		mediaType, mediaID, err := parseMediaRef(input)
+		if err != nil {
+			return "", "", fmt.Errorf("无效的ID '%s'，请输入TMDB ID、TMDB/IMDb链接或 tt1234567、tvdb:12345 等外部ID", input)
+		}
+		if preferType != "" && mediaType != preferType {
+			fmt.Printf("链接对应的是%s，已切换媒体类型\n", mediaLabel(mediaType))
+		}
+		return mediaType, mediaID, nil
+	}
+
+	fmt.Printf("正在通过 %s 查找TMDB条目...\n", ext)
+	mediaType, mediaID, err := f.findByExternalID(ext, preferType)
+	if err != nil {
+		return "", "", err
+	}
+	if preferType != "" && mediaType != preferType {
+		fmt.Printf("%s 对应的是%s，已切换媒体类型\n", ext, mediaLabel(mediaType))
+	}
+	fmt.Printf("%s → %s/%s\n", ext, mediaType, mediaID)
+	return mediaType, mediaID, nil
+}
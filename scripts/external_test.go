@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseExternalID(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    externalID
+		ok      bool
+		wantErr string
+	}{
+		{input: "tt1234567", want: externalID{source: "imdb_id", id: "tt1234567"}, ok: true},
+		{input: " TT1234567 ", want: externalID{source: "imdb_id", id: "tt1234567"}, ok: true},
+		{input: "imdb:tt1234567", want: externalID{source: "imdb_id", id: "tt1234567"}, ok: true},
+		{input: "IMDb: TT1234567", want: externalID{source: "imdb_id", id: "tt1234567"}, ok: true},
+		{input: "tvdb:12345", want: externalID{source: "tvdb_id", id: "12345"}, ok: true},
+		{input: "wikidata:Q123456", want: externalID{source: "wikidata_id", id: "Q123456"}, ok: true},
+		{input: "imdb:1234567", ok: true, wantErr: "无效的IMDb ID"},
+		{input: "tvdb:", ok: true, wantErr: "缺少ID"},
+		{input: "douban:1234567", ok: true, wantErr: "不支持通过豆瓣ID查找"},
+
+		// 不是外部ID，交给其他解析方式处理
+		{input: "842675"},
+		{input: "movie 842675"},
+		{input: "unknown:12345"},
+		{input: "tt12ab"},
+	}
+	for _, tt := range tests {
+		got, ok, err := parseExternalID(tt.input)
+		if ok != tt.ok {
+			t.Errorf("parseExternalID(%q) ok = %v, 期望 %v", tt.input, ok, tt.ok)
+		}
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseExternalID(%q) 错误 = %v, 期望包含 %q", tt.input, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseExternalID(%q) = %v, %v, 期望 %v", tt.input, got, err, tt.want)
+		}
+	}
+}
+
+func TestFindByExternalID(t *testing.T) {
+	f := newTestFetcher(t, jsonHandler(map[string]string{
+		"/find/tt0000001": `{"movie_results": [{"id": 100}], "tv_results": []}`,
+		"/find/tt0000002": `{"movie_results": [], "tv_results": [], "tv_episode_results": [{"id": 9, "show_id": 200}]}`,
+		"/find/tt0000003": `{"movie_results": [{"id": 300}], "tv_results": [{"id": 301}]}`,
+		"/find/tt0000004": `{"movie_results": [], "tv_results": []}`,
+	}))
+
+	tests := []struct {
+		id         string
+		preferType string
+		wantType   string
+		wantID     string
+		wantErr    string
+	}{
+		{id: "tt0000001", preferType: "tv", wantType: "movie", wantID: "100"},
+		{id: "tt0000002", preferType: "movie", wantType: "tv", wantID: "200"},
+		{id: "tt0000003", preferType: "tv", wantType: "tv", wantID: "301"},
+		{id: "tt0000003", wantErr: "同时对应电影 300 和电视剧 301"},
+		{id: "tt0000004", preferType: "movie", wantErr: "没有与 imdb_id:tt0000004 对应的电影或电视剧"},
+	}
+	for _, tt := range tests {
+		mediaType, mediaID, err := f.findByExternalID(externalID{source: "imdb_id", id: tt.id}, tt.preferType)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: 错误 = %v, 期望包含 %q", tt.id, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || mediaType != tt.wantType || mediaID != tt.wantID {
+			t.Errorf("%s: = %s/%s, %v, 期望 %s/%s", tt.id, mediaType, mediaID, err, tt.wantType, tt.wantID)
+		}
+	}
+
+	if _, _, err := f.findByExternalID(externalID{source: "imdb_id", id: "tt0000004"}, ""); !errors.Is(err, errNotFound) {
+		t.Errorf("没有结果时应返回 errNotFound, 实际为 %v", err)
+	}
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 检查结果的级别
+const (
+	lintError   = "error"
+	lintWarning = "warning"
+)
+
+// lintIssue 检查发现的一个问题
+type lintIssue struct {
+	File    string `json:"file"` // 相对于数据根目录的路径，使用 / 分隔
+	Level   string `json:"level"`
+	Message string `json:"message"`
+}
+
+// lintRequiredFiles 每个条目目录必须包含的文件
+var lintRequiredFiles = map[string][]string{
+	"movie": {"details.json", "release_dates.json"},
+	"tv":    {"details.json", "content_ratings.json"},
+}
+
+// lintRequiredKeys 各类数据文件必须包含的字段，key 为 "媒体类型/文件类型"
+var lintRequiredKeys = map[string][]string{
+	"movie/details":       {"id", "title", "original_title", "release_date"},
+	"movie/release_dates": {"id", "results"},
+	"tv/details":          {"id", "name", "original_name"},
+	"tv/content_ratings":  {"id", "results"},
+	"tv/season":           {"id", "season_number", "name"},
+	"tv/episode":          {"id", "season_number", "episode_number", "name"},
+}
+
+// lintDateLayouts 日期字段允许的格式，release_dates.json 中为带时间的格式 (如 2023-01-22T00:00:00.000Z)
+var lintDateLayouts = []string{"2006-01-02", time.RFC3339}
+
+// linter 检查整个数据目录
+type linter struct {
+	root   string
+	issues []lintIssue
+}
+
+func (l *linter) report(relPath, level, format string, a ...interface{}) {
+	l.issues = append(l.issues, lintIssue{File: relPath, Level: level, Message: fmt.Sprintf(format, a...)})
+}
+
+// lintTree 检查数据目录下的全部条目，返回按文件排序的问题列表
+func lintTree(root string) ([]lintIssue, error) {
+	if !checkDirectoryExists(root) {
+		return nil, fmt.Errorf("目录不存在: %s", root)
+	}
+
+	l := &linter{root: root}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, fmt.Errorf("读取 %s 失败: %v", root, err)
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		if _, ok := lintRequiredFiles[name]; !ok || !entry.IsDir() {
+			l.report(name, lintWarning, "未知的文件或目录，只应包含 movie/ 和 tv/")
+			continue
+		}
+		if err := l.lintTypeDir(name); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(l.issues, func(i, j int) bool {
+		if l.issues[i].File != l.issues[j].File {
+			return l.issues[i].File < l.issues[j].File
+		}
+		return l.issues[i].Message < l.issues[j].Message
+	})
+	return l.issues, nil
+}
+
+// lintTypeDir 检查 movie/ 或 tv/ 下的全部条目
+func (l *linter) lintTypeDir(mediaType string) error {
+	entries, err := os.ReadDir(filepath.Join(l.root, mediaType))
+	if err != nil {
+		return fmt.Errorf("读取 %s 失败: %v", mediaType, err)
+	}
+	for _, entry := range entries {
+		relDir := path.Join(mediaType, entry.Name())
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		if !entry.IsDir() {
+			l.report(relDir, lintWarning, "条目应为目录")
+			continue
+		}
+		if !isNumericID(entry.Name()) {
+			l.report(relDir, lintError, "目录名应为TMDB数字ID")
+			continue
+		}
+		if err := l.lintMediaDir(mediaType, entry.Name()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lintMediaDir 检查单个条目目录
+func (l *linter) lintMediaDir(mediaType, mediaID string) error {
+	relDir := path.Join(mediaType, mediaID)
+	dir := filepath.Join(l.root, mediaType, mediaID)
+
+	var files []string
+	err := filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("读取 %s 失败: %v", relDir, err)
+	}
+
+	present := make(map[string]bool, len(files))
+	for _, f := range files {
+		present[f] = true
+	}
+
+	// 使用覆盖文件维护的条目只检查覆盖文件本身
+	if present[overlayFileName] {
+		l.lintOverlay(relDir, filepath.Join(dir, overlayFileName))
+		// pack 需要在快照上应用覆盖内容，缺少快照时无法发布
+		snapshotDir := (&TMDBFetcher{outputDir: l.root}).snapshotDir(mediaType, mediaID)
+		if !checkDirectoryExists(snapshotDir) {
+			l.report(path.Join(relDir, overlayFileName), lintError, "使用覆盖文件维护，但 %s 中没有TMDB原始数据快照，无法生成完整的数据文件", upstreamDirName)
+		}
+		for _, f := range files {
+			if f != overlayFileName {
+				l.report(path.Join(relDir, f), lintWarning, "使用覆盖文件的条目目录中不应有其他文件")
+			}
+		}
+		return nil
+	}
+
+	for _, required := range lintRequiredFiles[mediaType] {
+		if !present[required] {
+			l.report(path.Join(relDir, required), lintError, "缺少必需的文件")
+		}
+	}
+
+	for _, f := range files {
+		relPath := path.Join(relDir, f)
+		kind, number := lintFileKind(mediaType, f)
+		if kind == "" {
+			l.report(relPath, lintWarning, "未知的文件")
+			continue
+		}
+
+		data, err := lintLoadJSON(filepath.Join(dir, filepath.FromSlash(f)))
+		if err != nil {
+			l.report(relPath, lintError, "%v", err)
+			continue
+		}
+
+		for _, key := range lintRequiredKeys[mediaType+"/"+kind] {
+			if _, ok := data[key]; !ok {
+				l.report(relPath, lintError, "缺少必需的字段 %s", key)
+			}
+		}
+
+		switch kind {
+		case "details", "release_dates", "content_ratings":
+			l.checkNumber(relPath, data, "id", mediaID)
+		case "season":
+			l.checkNumber(relPath, data, "season_number", number[0])
+		case "episode":
+			l.checkNumber(relPath, data, "season_number", number[0])
+			l.checkNumber(relPath, data, "episode_number", number[1])
+		}
+
+		l.checkDates(relPath, "", data)
+	}
+	return nil
+}
+
+// lintFileKind 根据相对路径判断文件类型，季/集文件同时返回路径中的季号、集号
+func lintFileKind(mediaType, relPath string) (string, []string) {
+	parts := strings.Split(relPath, "/")
+	switch {
+	case len(parts) == 1:
+		name := strings.TrimSuffix(parts[0], ".json")
+		for _, required := range lintRequiredFiles[mediaType] {
+			if parts[0] == required {
+				return name, nil
+			}
+		}
+	case mediaType != "tv" || parts[len(parts)-1] != "details.json":
+	case len(parts) == 3 && parts[0] == "season" && isNumericID(parts[1]):
+		return "season", []string{parts[1]}
+	case len(parts) == 5 && parts[0] == "season" && isNumericID(parts[1]) && parts[2] == "episode" && isNumericID(parts[3]):
+		return "episode", []string{parts[1], parts[3]}
+	}
+	return "", nil
+}
+
+// lintLoadJSON 读取JSON对象，错误信息中不重复文件路径
+func lintLoadJSON(filePath string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("读取失败: %v", err)
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("JSON解析失败: %v", err)
+	}
+	if result == nil {
+		return nil, fmt.Errorf("内容应为JSON对象")
+	}
+	return result, nil
+}
+
+// checkNumber 检查数字字段与目录名一致
+func (l *linter) checkNumber(relPath string, data map[string]interface{}, key, expected string) {
+	v, ok := data[key]
+	if !ok {
+		return
+	}
+	n, ok := v.(float64)
+	if !ok {
+		l.report(relPath, lintError, "%s 应为数字，实际为 %s", key, formatValue(v))
+		return
+	}
+	if actual := strconv.FormatFloat(n, 'f', -1, 64); actual != expected {
+		l.report(relPath, lintError, "%s 为 %s，与目录名 %s 不一致", key, actual, expected)
+	}
+}
+
+// checkDates 递归检查所有日期字段 (以 _date 结尾的字段)，空值表示未知日期
+func (l *linter) checkDates(relPath, keyPath string, v interface{}) {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for k, child := range value {
+			childPath := joinPath(keyPath, k)
+			if strings.HasSuffix(k, "_date") {
+				l.checkDate(relPath, childPath, child)
+				continue
+			}
+			l.checkDates(relPath, childPath, child)
+		}
+	case []interface{}:
+		for i, child := range value {
+			l.checkDates(relPath, keyPath+"["+strconv.Itoa(i)+"]", child)
+		}
+	}
+}
+
+func (l *linter) checkDate(relPath, keyPath string, v interface{}) {
+	if v == nil {
+		return
+	}
+	s, ok := v.(string)
+	if !ok {
+		l.report(relPath, lintError, "%s 应为日期字符串，实际为 %s", keyPath, formatValue(v))
+		return
+	}
+	if s == "" {
+		return
+	}
+	for _, layout := range lintDateLayouts {
+		if _, err := time.Parse(layout, s); err == nil {
+			return
+		}
+	}
+	l.report(relPath, lintError, "%s 不是有效的日期: %q", keyPath, s)
+}
+
+// lintOverlay 检查覆盖文件的格式
+func (l *linter) lintOverlay(relDir, overlayPath string) {
+	relPath := path.Join(relDir, overlayFileName)
+	data, err := lintLoadJSON(overlayPath)
+	if err != nil {
+		l.report(relPath, lintError, "%v", err)
+		return
+	}
+	mediaType := path.Dir(relDir)
+	for file, patch := range data {
+		if kind, _ := lintFileKind(mediaType, file); kind == "" {
+			l.report(relPath, lintWarning, "覆盖了未知的文件 %s", file)
+		}
+		if _, ok := patch.(map[string]interface{}); !ok && patch != nil {
+			l.report(relPath, lintError, "%s 的内容必须是对象或 null", file)
+			continue
+		}
+		l.checkDates(relPath, file, patch)
+	}
+}
+
+// printLintIssues 按格式输出检查结果，返回错误数量
+// text 格式每行一条: 文件: 级别: 说明；json 格式输出问题数组
+func printLintIssues(w io.Writer, issues []lintIssue, format string) (int, error) {
+	errorCount := 0
+	for _, issue := range issues {
+		if issue.Level == lintError {
+			errorCount++
+		}
+	}
+
+	if format == "json" {
+		if issues == nil {
+			issues = []lintIssue{}
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		encoder.SetEscapeHTML(false)
+		return errorCount, encoder.Encode(issues)
+	}
+
+	for _, issue := range issues {
+		fmt.Fprintf(w, "%s: %s: %s\n", issue.File, issue.Level, issue.Message)
+	}
+	fmt.Fprintf(w, "\n共 %d 个错误, %d 个警告\n", errorCount, len(issues)-errorCount)
+	return errorCount, nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"tmdb-manager/updater"
+)
+
+// releaseTimeZone 发布版本号使用的时区，与发布流程中 date -d '+8 hours' 一致
+var releaseTimeZone = time.FixedZone("UTC+8", 8*60*60)
+
+// releaseVersion 返回时间对应的发布版本号，格式与发布流程的 tag 相同，如 202401021504
+func releaseVersion(t time.Time) string {
+	return t.In(releaseTimeZone).Format("200601021504")
+}
+
+// buildManifest 按条目整理发布包中的文件，计算每个文件的 SHA256 并查找最后修改的提交
+func (f *TMDBFetcher) buildManifest(version, repoDir string, files []packFile) (*updater.Manifest, error) {
+	manifest := &updater.Manifest{
+		Version: version,
+		Files:   len(files),
+		Counts:  map[string]int{"movie": 0, "tv": 0},
+		Entries: []updater.Entry{},
+	}
+
+	entries := make(map[mediaKey]*updater.Entry)
+	var keys []mediaKey
+	for _, file := range files {
+		parts := strings.SplitN(file.relPath, "/", 3)
+		if len(parts) < 3 || (parts[0] != "movie" && parts[0] != "tv") || !isNumericID(parts[1]) {
+			continue
+		}
+		key := mediaKey{mediaType: parts[0], mediaID: parts[1]}
+		entry, ok := entries[key]
+		if !ok {
+			entry = &updater.Entry{Type: key.mediaType, ID: key.mediaID, Overlay: f.hasOverlay(key.mediaType, key.mediaID)}
+			entries[key] = entry
+			keys = append(keys, key)
+		}
+
+		sum := sha256.Sum256(file.data)
+		entry.Files = append(entry.Files, updater.File{Path: parts[2], Size: int64(len(file.data)), SHA256: hex.EncodeToString(sum[:])})
+		if parts[2] == "details.json" {
+			var details map[string]interface{}
+			if err := json.Unmarshal(file.data, &details); err != nil {
+				return nil, fmt.Errorf("解析 %s 失败: %v", file.relPath, err)
+			}
+			entry.Title = mediaTitle(key.mediaType, details)
+		}
+	}
+
+	if repoDir != "" {
+		absRepoDir, err := resolveGitRepo(repoDir)
+		if err != nil {
+			return nil, err
+		}
+		if manifest.Commit, err = gitOutput(absRepoDir, "rev-parse", "HEAD"); err != nil {
+			return nil, fmt.Errorf("获取当前提交失败: %v", err)
+		}
+		for _, key := range keys {
+			if err := f.fillLastCommit(absRepoDir, key, entries[key]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+	for _, key := range keys {
+		manifest.Entries = append(manifest.Entries, *entries[key])
+		manifest.Counts[key.mediaType]++
+	}
+	return manifest, nil
+}
+
+// fillLastCommit 查找最后修改条目的提交，使用覆盖文件的条目同时考虑 tmdb_upstream 中的快照
+func (f *TMDBFetcher) fillLastCommit(absRepoDir string, key mediaKey, entry *updater.Entry) error {
+	dirs := []string{f.mediaDir(key.mediaType, key.mediaID)}
+	if entry.Overlay {
+		dirs = append(dirs, f.snapshotDir(key.mediaType, key.mediaID))
+	}
+
+	args := []string{"log", "-1", "--format=%H %cI", "--"}
+	for _, dir := range dirs {
+		absDir, err := filepath.Abs(dir)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(absRepoDir, absDir)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			return fmt.Errorf("%s 不在项目目录 %s 中", dir, absRepoDir)
+		}
+		args = append(args, filepath.ToSlash(rel))
+	}
+
+	output, err := gitOutput(absRepoDir, args...)
+	if err != nil {
+		return fmt.Errorf("查找 %s 最后修改的提交失败: %v", key, err)
+	}
+	// 尚未提交的条目没有提交记录
+	if hash, commitTime, ok := strings.Cut(output, " "); ok {
+		entry.Commit, entry.CommitTime = hash, commitTime
+	}
+	return nil
+}
+
+// gitOutput 在项目目录中执行git命令，返回去掉首尾空白的输出
+func gitOutput(repoDir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", repoDir}, args...)...)
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("%v: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
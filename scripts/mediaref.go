@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+)
+
+// parseMediaRef 解析媒体引用，返回媒体类型(movie/tv)和TMDB ID
+// 支持以下格式:
+//
+//	movie 842675
+//	tv/95480
+//	https://www.themoviedb.org/movie/842675-the-wandering-earth-ii
+//	https://www.themoviedb.org/tv/95480/season/2
+func parseMediaRef(input string) (string, string, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return "", "", fmt.Errorf("内容为空")
+	}
+
+	if isTMDBURL(input) {
+		return parseTMDBURL(input)
+	}
+
+	parts := strings.FieldsFunc(input, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '/'
+	})
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("无法识别 '%s'，格式应为 'movie 842675' 或 'tv/95480'", input)
+	}
+
+	mediaType := strings.ToLower(parts[0])
+	if mediaType != "movie" && mediaType != "tv" {
+		return "", "", fmt.Errorf("无效的媒体类型 '%s'，可选: movie, tv", parts[0])
+	}
+	if !isNumericID(parts[1]) {
+		return "", "", fmt.Errorf("无效的TMDB ID '%s'", parts[1])
+	}
+
+	return mediaType, parts[1], nil
+}
+
+// isTMDBURL 判断输入是否为TMDB网站链接
+func isTMDBURL(input string) bool {
+	return isSiteURL(input, "themoviedb.org")
+}
+
+// isSiteURL 判断输入是否为指定网站 (含子域名，如 www.、m.、movie.) 的链接，协议可以省略
+func isSiteURL(input, domain string) bool {
+	lower := strings.ToLower(strings.TrimSpace(input))
+	lower = strings.TrimPrefix(lower, "https://")
+	lower = strings.TrimPrefix(lower, "http://")
+	host := lower
+	if idx := strings.IndexAny(host, "/?#"); idx >= 0 {
+		host = host[:idx]
+	}
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// isMediaRef 判断输入是否可以直接确定条目 (链接、外部ID或 "movie 842675" 形式)，不需要再选择媒体类型
+func isMediaRef(input string) bool {
+	if _, ok, _ := parseExternalID(input); ok {
+		return true
+	}
+	_, _, err := parseMediaRef(input)
+	return err == nil
+}
+
+// parseTMDBURL 从TMDB网站链接中解析媒体类型和ID，ID后的slug和子路径会被忽略
+func parseTMDBURL(input string) (string, string, error) {
+	if !strings.Contains(input, "://") {
+		input = "https://" + input
+	}
+	u, err := url.Parse(input)
+	if err != nil {
+		return "", "", fmt.Errorf("无效的链接 '%s': %v", input, err)
+	}
+
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i := 0; i+1 < len(segments); i++ {
+		if segments[i] != "movie" && segments[i] != "tv" {
+			continue
+		}
+		// 842675-the-wandering-earth-ii -> 842675
+		id := segments[i+1]
+		if idx := strings.Index(id, "-"); idx >= 0 {
+			id = id[:idx]
+		}
+		if isNumericID(id) {
+			return segments[i], id, nil
+		}
+	}
+
+	return "", "", fmt.Errorf("无法从链接中识别媒体类型和ID: %s", input)
+}
+
+// isNumericID 判断是否为纯数字ID
+func isNumericID(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, r := range id {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseMediaRef(t *testing.T) {
+	tests := []struct {
+		input    string
+		wantType string
+		wantID   string
+		wantErr  string
+	}{
+		{input: "movie 842675", wantType: "movie", wantID: "842675"},
+		{input: " TV/95480 ", wantType: "tv", wantID: "95480"},
+		{input: "https://www.themoviedb.org/movie/842675-the-wandering-earth-ii", wantType: "movie", wantID: "842675"},
+		{input: "https://www.themoviedb.org/movie/842675-the-wandering-earth-ii?language=zh-CN", wantType: "movie", wantID: "842675"},
+		{input: "https://www.themoviedb.org/tv/95480-three-body/season/2", wantType: "tv", wantID: "95480"},
+		{input: "themoviedb.org/tv/95480", wantType: "tv", wantID: "95480"},
+		{input: "http://m.themoviedb.org/movie/842675#overview", wantType: "movie", wantID: "842675"},
+
+		{input: "", wantErr: "内容为空"},
+		{input: "842675", wantErr: "格式应为"},
+		{input: "person 12345", wantErr: "无效的媒体类型"},
+		{input: "movie abc", wantErr: "无效的TMDB ID"},
+		{input: "https://www.themoviedb.org/person/12345-someone", wantErr: "无法从链接中识别"},
+		{input: "https://www.themoviedb.org/movie/the-wandering-earth", wantErr: "无法从链接中识别"},
+		{input: "https://notthemoviedb.org/movie/842675", wantErr: "无法识别"},
+	}
+	for _, tt := range tests {
+		mediaType, mediaID, err := parseMediaRef(tt.input)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseMediaRef(%q) 错误 = %v, 期望包含 %q", tt.input, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || mediaType != tt.wantType || mediaID != tt.wantID {
+			t.Errorf("parseMediaRef(%q) = %s/%s, %v, 期望 %s/%s", tt.input, mediaType, mediaID, err, tt.wantType, tt.wantID)
+		}
+	}
+}
+
+func TestParseExternalIDLinks(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    externalID
+		wantErr error
+	}{
+		{input: "https://www.imdb.com/title/tt1234567/", want: externalID{source: "imdb_id", id: "tt1234567"}},
+		{input: "m.imdb.com/title/tt1234567/?ref_=nv_sr_1", want: externalID{source: "imdb_id", id: "tt1234567"}},
+		{input: "https://movie.douban.com/subject/35267208/", wantErr: errDoubanUnsupported},
+		{input: "douban.com/subject/35267208", wantErr: errDoubanUnsupported},
+		{input: "douban:35267208", wantErr: errDoubanUnsupported},
+	}
+	for _, tt := range tests {
+		got, ok, err := parseExternalID(tt.input)
+		if !ok {
+			t.Errorf("parseExternalID(%q) 没有识别为外部ID", tt.input)
+		}
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("parseExternalID(%q) 错误 = %v, 期望 %v", tt.input, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseExternalID(%q) = %v, %v, 期望 %v", tt.input, got, err, tt.want)
+		}
+	}
+
+	if _, ok, err := parseExternalID("https://www.imdb.com/name/nm0000001/"); !ok || err == nil || !strings.Contains(err.Error(), "无法从IMDb链接中识别ID") {
+		t.Errorf("不是作品页面的IMDb链接应返回错误, 实际为 %v, %v", ok, err)
+	}
+}
+
+func TestIsMediaRef(t *testing.T) {
+	for input, want := range map[string]bool{
+		"tv 95480": true,
+		"https://www.themoviedb.org/movie/842675-the-wandering-earth-ii": true,
+		"https://www.imdb.com/title/tt1234567/":                          true,
+		"tvdb:12345":                                                     true,
+		"https://movie.douban.com/subject/35267208/":                     true, // 由解析时的错误说明原因
+		"842675":      false,
+		"流浪地球":        false,
+		"unknown:123": false,
+	} {
+		if got := isMediaRef(input); got != want {
+			t.Errorf("isMediaRef(%q) = %v, 期望 %v", input, got, want)
+		}
+	}
+}
+
+func TestResolveMediaID(t *testing.T) {
+	f := newTestFetcher(t, jsonHandler(map[string]string{
+		"/find/tt1234567": `{"movie_results": [], "tv_results": [{"id": 95480}]}`,
+	}))
+
+	tests := []struct {
+		preferType string
+		input      string
+		wantType   string
+		wantID     string
+		wantErr    string
+	}{
+		{preferType: "movie", input: "842675", wantType: "movie", wantID: "842675"},
+		{preferType: "movie", input: "https://www.themoviedb.org/tv/95480-three-body", wantType: "tv", wantID: "95480"},
+		{preferType: "movie", input: "https://www.imdb.com/title/tt1234567/", wantType: "tv", wantID: "95480"},
+		{input: "842675", wantErr: "缺少媒体类型"},
+		{preferType: "movie", input: "https://movie.douban.com/subject/35267208/", wantErr: "不支持通过豆瓣ID查找"},
+		{preferType: "movie", input: "流浪地球", wantErr: "无效的ID"},
+	}
+	for _, tt := range tests {
+		mediaType, mediaID, err := f.resolveMediaID(tt.preferType, tt.input)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("resolveMediaID(%q, %q) 错误 = %v, 期望包含 %q", tt.preferType, tt.input, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || mediaType != tt.wantType || mediaID != tt.wantID {
+			t.Errorf("resolveMediaID(%q, %q) = %s/%s, %v, 期望 %s/%s", tt.preferType, tt.input, mediaType, mediaID, err, tt.wantType, tt.wantID)
+		}
+	}
+}
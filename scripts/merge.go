@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// absentValue 表示某一方不存在该字段/元素，用于区分 "字段不存在" 与 "字段值为 null"
+type absentValue struct{}
+
+var absent interface{} = absentValue{}
+
+// keySeparator 组合识别值的分隔符
+const keySeparator = "\x00"
+
+// mergeConflict 三方合并中本地和TMDB都修改了同一字段且结果不同
+type mergeConflict struct {
+	path   string      // 字段路径，如 "credits.cast[id=64].character"
+	base   interface{} // 快照中的值
+	local  interface{} // 本地维护的值 (合并结果保留该值)
+	remote interface{} // TMDB最新值
+}
+
+// arrayKeyFields 数组元素的识别字段，按顺序尝试
+// 数组中所有元素都具有这些字段且组合值互不重复时，按元素逐个合并，否则把整个数组当作一个值
+var arrayKeyFields = [][]string{
+	{"credit_id"},
+	{"id"},
+	{"iso_3166_1", "iso_639_1"},
+	{"iso_3166_1", "title"},
+	{"iso_639_1"},
+	{"iso_3166_1"},
+	{"season_number"},
+	{"episode_number"},
+}
+
+// merge3 以快照(base)为基准，合并本地维护的数据(local)和TMDB最新数据(remote)
+//   - 只有TMDB修改的字段采用TMDB的值（包括新增字段、新演员、新季等）
+//   - 只有本地修改的字段保留本地的值
+//   - 双方都修改且结果不同时保留本地的值，并记录为冲突
+//
+// 返回 absent 表示该字段在合并结果中应被删除
+func merge3(path string, base, local, remote interface{}, conflicts *[]mergeConflict) interface{} {
+	switch {
+	case reflect.DeepEqual(local, remote):
+		return local
+	case reflect.DeepEqual(base, local):
+		return remote
+	case reflect.DeepEqual(base, remote):
+		return local
+	}
+
+	// 双方都修改了，尝试逐层合并
+	localMap, localIsMap := local.(map[string]interface{})
+	remoteMap, remoteIsMap := remote.(map[string]interface{})
+	if localIsMap && remoteIsMap {
+		baseMap, _ := base.(map[string]interface{})
+		return mergeMaps(path, baseMap, localMap, remoteMap, conflicts)
+	}
+
+	localArr, localIsArr := local.([]interface{})
+	remoteArr, remoteIsArr := remote.([]interface{})
+	if localIsArr && remoteIsArr {
+		baseArr, _ := base.([]interface{})
+		if merged, ok := mergeArrays(path, baseArr, localArr, remoteArr, conflicts); ok {
+			return merged
+		}
+	}
+
+	*conflicts = append(*conflicts, mergeConflict{path: path, base: base, local: local, remote: remote})
+	return local
+}
+
+// mergeMaps 逐字段合并对象
+func mergeMaps(path string, base, local, remote map[string]interface{}, conflicts *[]mergeConflict) map[string]interface{} {
+	keys := make(map[string]bool)
+	for _, m := range []map[string]interface{}{base, local, remote} {
+		for k := range m {
+			keys[k] = true
+		}
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	result := make(map[string]interface{}, len(sorted))
+	for _, k := range sorted {
+		v := merge3(joinPath(path, k), lookup(base, k), lookup(local, k), lookup(remote, k), conflicts)
+		if v != absent {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+// mergeArrays 按识别字段逐个合并数组元素，无法识别元素时返回 false
+// 结果保持本地的顺序，TMDB新增的元素追加在末尾
+func mergeArrays(path string, base, local, remote []interface{}, conflicts *[]mergeConflict) ([]interface{}, bool) {
+	fields := arrayKeyFor(base, local, remote)
+	if fields == nil {
+		return nil, false
+	}
+
+	baseIndex := indexByKey(base, fields)
+	remoteIndex := indexByKey(remote, fields)
+	localIndex := indexByKey(local, fields)
+
+	result := make([]interface{}, 0, len(local))
+	for _, l := range local {
+		key := elementKey(l, fields)
+		v := merge3(elementPath(path, fields, key), lookupKey(baseIndex, key), l, lookupKey(remoteIndex, key), conflicts)
+		if v != absent {
+			result = append(result, v)
+		}
+	}
+	for _, r := range remote {
+		key := elementKey(r, fields)
+		if _, ok := localIndex[key]; ok {
+			continue
+		}
+		v := merge3(elementPath(path, fields, key), lookupKey(baseIndex, key), absent, r, conflicts)
+		if v != absent {
+			result = append(result, v)
+		}
+	}
+	return result, true
+}
+
+// arrayKeyFor 为数组选出可用的识别字段
+func arrayKeyFor(arrays ...[]interface{}) []string {
+	for _, fields := range arrayKeyFields {
+		ok := true
+		for _, arr := range arrays {
+			if !uniqueByKey(arr, fields) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return fields
+		}
+	}
+	return nil
+}
+
+// uniqueByKey 判断数组中所有元素都具有识别字段且互不重复
+func uniqueByKey(arr []interface{}, fields []string) bool {
+	seen := make(map[string]bool, len(arr))
+	for _, elem := range arr {
+		key := elementKey(elem, fields)
+		if key == "" || seen[key] {
+			return false
+		}
+		seen[key] = true
+	}
+	return true
+}
+
+// elementKey 返回数组元素的识别值，缺少识别字段时返回空字符串
+func elementKey(elem interface{}, fields []string) string {
+	m, ok := elem.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	parts := make([]string, len(fields))
+	for i, field := range fields {
+		v, ok := m[field]
+		if !ok || v == nil {
+			return ""
+		}
+		if n, ok := v.(float64); ok {
+			parts[i] = strconv.FormatFloat(n, 'f', -1, 64)
+		} else {
+			parts[i] = fmt.Sprint(v)
+		}
+	}
+	return strings.Join(parts, keySeparator)
+}
+
+func indexByKey(arr []interface{}, fields []string) map[string]interface{} {
+	index := make(map[string]interface{}, len(arr))
+	for _, elem := range arr {
+		index[elementKey(elem, fields)] = elem
+	}
+	return index
+}
+
+func lookup(m map[string]interface{}, key string) interface{} {
+	if v, ok := m[key]; ok {
+		return v
+	}
+	return absent
+}
+
+func lookupKey(index map[string]interface{}, key string) interface{} {
+	if v, ok := index[key]; ok {
+		return v
+	}
+	return absent
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// elementPath 返回数组元素的路径，如 cast[id=64]
+func elementPath(path string, fields []string, key string) string {
+	values := strings.Split(key, keySeparator)
+	parts := make([]string, len(fields))
+	for i, field := range fields {
+		parts[i] = field + "=" + values[i]
+	}
+	return path + "[" + strings.Join(parts, ",") + "]"
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// decodeJSON 解析测试用的JSON，空字符串表示不存在 (absent)
+func decodeJSON(t *testing.T, s string) interface{} {
+	t.Helper()
+	if s == "" {
+		return absent
+	}
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatalf("解析 %s 失败: %v", s, err)
+	}
+	return v
+}
+
+func TestMerge3(t *testing.T) {
+	tests := []struct {
+		name      string
+		base      string
+		local     string
+		remote    string
+		want      string
+		conflicts []string
+	}{
+		{
+			name:   "只有本地修改",
+			base:   `{"title": "流浪地球Ⅱ", "runtime": 173}`,
+			local:  `{"title": "流浪地球2", "runtime": 173}`,
+			remote: `{"title": "流浪地球Ⅱ", "runtime": 173}`,
+			want:   `{"title": "流浪地球2", "runtime": 173}`,
+		},
+		{
+			name:   "只有TMDB修改，包括新增字段",
+			base:   `{"title": "流浪地球2"}`,
+			local:  `{"title": "流浪地球2"}`,
+			remote: `{"title": "流浪地球2", "tagline": "希望"}`,
+			want:   `{"title": "流浪地球2", "tagline": "希望"}`,
+		},
+		{
+			name:   "双方修改不同字段",
+			base:   `{"title": "A", "vote_count": 1}`,
+			local:  `{"title": "B", "vote_count": 1}`,
+			remote: `{"title": "A", "vote_count": 2}`,
+			want:   `{"title": "B", "vote_count": 2}`,
+		},
+		{
+			name:      "双方修改同一字段时保留本地值",
+			base:      `{"title": "A"}`,
+			local:     `{"title": "B"}`,
+			remote:    `{"title": "C"}`,
+			want:      `{"title": "B"}`,
+			conflicts: []string{"title"},
+		},
+		{
+			name:   "本地删除的字段",
+			base:   `{"title": "A", "tagline": "x"}`,
+			local:  `{"title": "A"}`,
+			remote: `{"title": "A", "tagline": "x"}`,
+			want:   `{"title": "A"}`,
+		},
+		{
+			name:   "TMDB删除的字段",
+			base:   `{"title": "A", "tagline": "x"}`,
+			local:  `{"title": "A", "tagline": "x"}`,
+			remote: `{"title": "A"}`,
+			want:   `{"title": "A"}`,
+		},
+		{
+			name:   "null 与不存在不同",
+			base:   `{"backdrop_path": "/a.jpg"}`,
+			local:  `{"backdrop_path": null}`,
+			remote: `{"backdrop_path": "/a.jpg"}`,
+			want:   `{"backdrop_path": null}`,
+		},
+		{
+			name:   "数组按 id 逐个合并，TMDB新增的元素追加在末尾",
+			base:   `{"cast": [{"id": 1, "character": "A"}, {"id": 2, "character": "B"}]}`,
+			local:  `{"cast": [{"id": 1, "character": "刘培强"}, {"id": 2, "character": "B"}]}`,
+			remote: `{"cast": [{"id": 1, "character": "A"}, {"id": 2, "character": "B2"}, {"id": 3, "character": "C"}]}`,
+			want:   `{"cast": [{"id": 1, "character": "刘培强"}, {"id": 2, "character": "B2"}, {"id": 3, "character": "C"}]}`,
+		},
+		{
+			name:      "数组元素的冲突",
+			base:      `{"cast": [{"id": 1, "character": "A"}]}`,
+			local:     `{"cast": [{"id": 1, "character": "L"}]}`,
+			remote:    `{"cast": [{"id": 1, "character": "R"}]}`,
+			want:      `{"cast": [{"id": 1, "character": "L"}]}`,
+			conflicts: []string{"cast[id=1].character"},
+		},
+		{
+			name:      "无法识别元素的数组整体比较",
+			base:      `{"origin_country": ["CN"]}`,
+			local:     `{"origin_country": ["CN", "HK"]}`,
+			remote:    `{"origin_country": ["US"]}`,
+			want:      `{"origin_country": ["CN", "HK"]}`,
+			conflicts: []string{"origin_country"},
+		},
+		{
+			name:      "没有快照时不同的字段都是冲突，只有一方有的字段直接采用",
+			base:      "",
+			local:     `{"title": "A", "overview": "本地"}`,
+			remote:    `{"title": "A", "overview": "TMDB", "tagline": "新"}`,
+			want:      `{"title": "A", "overview": "本地", "tagline": "新"}`,
+			conflicts: []string{"overview"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var conflicts []mergeConflict
+			got := merge3("", decodeJSON(t, tt.base), decodeJSON(t, tt.local), decodeJSON(t, tt.remote), &conflicts)
+			if want := decodeJSON(t, tt.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("合并结果 = %v, 期望 %v", got, want)
+			}
+			var paths []string
+			for _, c := range conflicts {
+				paths = append(paths, c.path)
+			}
+			if !reflect.DeepEqual(paths, tt.conflicts) {
+				t.Errorf("冲突 = %v, 期望 %v", paths, tt.conflicts)
+			}
+		})
+	}
+}
+
+func TestMerge3ConflictWithoutBase(t *testing.T) {
+	var conflicts []mergeConflict
+	merge3("", absent, decodeJSON(t, `{"name": "A"}`), decodeJSON(t, `{"name": "B"}`), &conflicts)
+	if len(conflicts) != 1 || conflicts[0].base != absent {
+		t.Fatalf("冲突 = %+v, 期望一个快照值为 absent 的冲突", conflicts)
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	// RFC 7386 附录A 中的示例
+	tests := []struct {
+		target, patch, want string
+	}{
+		{`{"a": "b"}`, `{"a": "c"}`, `{"a": "c"}`},
+		{`{"a": "b"}`, `{"b": "c"}`, `{"a": "b", "b": "c"}`},
+		{`{"a": "b"}`, `{"a": null}`, `{}`},
+		{`{"a": "b", "b": "c"}`, `{"a": null}`, `{"b": "c"}`},
+		{`{"a": ["b"]}`, `{"a": "c"}`, `{"a": "c"}`},
+		{`{"a": "c"}`, `{"a": ["b"]}`, `{"a": ["b"]}`},
+		{`{"a": {"b": "c"}}`, `{"a": {"b": "d", "c": null}}`, `{"a": {"b": "d"}}`},
+		{`{"a": [{"b": "c"}]}`, `{"a": [1]}`, `{"a": [1]}`},
+		{`{"e": null}`, `{"a": 1}`, `{"e": null, "a": 1}`},
+		{`[1, 2]`, `{"a": "b", "c": null}`, `{"a": "b"}`},
+		{`{}`, `{"a": {"bb": {"ccc": null}}}`, `{"a": {"bb": {}}}`},
+	}
+	for _, tt := range tests {
+		target := decodeJSON(t, tt.target)
+		got := mergePatch(target, decodeJSON(t, tt.patch))
+		if want := decodeJSON(t, tt.want); !reflect.DeepEqual(got, want) {
+			t.Errorf("mergePatch(%s, %s) = %v, 期望 %v", tt.target, tt.patch, got, want)
+		}
+		if !reflect.DeepEqual(target, decodeJSON(t, tt.target)) {
+			t.Errorf("mergePatch 修改了 target %s", tt.target)
+		}
+	}
+}
+
+func TestCreateMergePatchRoundTrip(t *testing.T) {
+	tests := []struct {
+		name           string
+		source, target string
+	}{
+		{"修改字段", `{"title": "A", "id": 1}`, `{"title": "B", "id": 1}`},
+		{"新增字段", `{"id": 1}`, `{"id": 1, "tagline": "x"}`},
+		{"删除字段", `{"id": 1, "tagline": "x"}`, `{"id": 1}`},
+		{"嵌套对象", `{"credits": {"cast": [], "crew": []}}`, `{"credits": {"cast": [{"id": 1}], "crew": []}}`},
+		{"数组整体替换", `{"genres": [{"id": 1}, {"id": 2}]}`, `{"genres": [{"id": 2}]}`},
+		{"两边都有的 null 不写入补丁", `{"backdrop_path": null, "title": "A"}`, `{"backdrop_path": null, "title": "B"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, target := decodeJSON(t, tt.source), decodeJSON(t, tt.target)
+			patch, changed := createMergePatch(source, target)
+			if !changed {
+				t.Fatal("createMergePatch 返回无变化")
+			}
+			if got := mergePatch(source, patch); !reflect.DeepEqual(got, target) {
+				t.Errorf("应用补丁 %v 后 = %v, 期望 %v", patch, got, target)
+			}
+		})
+	}
+
+	if _, changed := createMergePatch(decodeJSON(t, `{"a": [1, {"b": null}]}`), decodeJSON(t, `{"a": [1, {"b": null}]}`)); changed {
+		t.Error("相同的值应返回无变化")
+	}
+}
+
+// 覆盖文件中的 null 表示删除字段，TMDB有值而本地为 null 时无法还原，convertToOverlay 据此拒绝转换
+func TestCreateMergePatchNullIsLossy(t *testing.T) {
+	remote := mediaFiles{"details.json": {"title": "A", "backdrop_path": "/a.jpg"}}
+	local := map[string]interface{}{"title": "A", "backdrop_path": nil}
+	patch, _ := createMergePatch(remote["details.json"], local)
+	expanded := applyOverlay(remote, overlay{"details.json": patch})
+	if reflect.DeepEqual(expanded["details.json"], local) {
+		t.Fatal("期望展开后丢失本地的 null 值")
+	}
+	if _, ok := expanded["details.json"]["backdrop_path"]; ok {
+		t.Errorf("展开结果 = %v, 期望删除 backdrop_path", expanded["details.json"])
+	}
+}
@@ -0,0 +1,223 @@
+package models
+
+import "time"
+
+// Date TMDB中的日期，如 "2023-01-22"，未知日期为空字符串
+// 保存原始文本以保证往返无损，需要比较时使用 Time 解析
+type Date string
+
+// dateLayouts 支持的日期格式，release_dates.json 中的日期带有时间
+var dateLayouts = []string{"2006-01-02", time.RFC3339}
+
+// Time 解析日期
+func (d Date) Time() (time.Time, error) {
+	var err error
+	for _, layout := range dateLayouts {
+		var t time.Time
+		if t, err = time.Parse(layout, string(d)); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// Year 返回日期中的年份，未知日期返回空字符串
+func (d Date) Year() string {
+	if len(d) < 4 {
+		return ""
+	}
+	return string(d[:4])
+}
+
+// Genre 类型
+type Genre struct {
+	Unknown `json:"-"`
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+}
+
+func (m *Genre) UnmarshalJSON(data []byte) error {
+	type plain Genre
+	return decodeObject(data, (*plain)(m), &m.Unknown)
+}
+
+func (m Genre) MarshalJSON() ([]byte, error) {
+	type plain Genre
+	return encodeObject(plain(m), m.Unknown)
+}
+
+// ProductionCompany 出品公司
+type ProductionCompany struct {
+	Unknown       `json:"-"`
+	ID            int     `json:"id"`
+	LogoPath      *string `json:"logo_path"`
+	Name          string  `json:"name"`
+	OriginCountry string  `json:"origin_country"`
+}
+
+func (m *ProductionCompany) UnmarshalJSON(data []byte) error {
+	type plain ProductionCompany
+	return decodeObject(data, (*plain)(m), &m.Unknown)
+}
+
+func (m ProductionCompany) MarshalJSON() ([]byte, error) {
+	type plain ProductionCompany
+	return encodeObject(plain(m), m.Unknown)
+}
+
+// ProductionCountry 出品国家
+type ProductionCountry struct {
+	Unknown   `json:"-"`
+	ISO3166_1 string `json:"iso_3166_1"`
+	Name      string `json:"name"`
+}
+
+func (m *ProductionCountry) UnmarshalJSON(data []byte) error {
+	type plain ProductionCountry
+	return decodeObject(data, (*plain)(m), &m.Unknown)
+}
+
+func (m ProductionCountry) MarshalJSON() ([]byte, error) {
+	type plain ProductionCountry
+	return encodeObject(plain(m), m.Unknown)
+}
+
+// SpokenLanguage 对白语言
+type SpokenLanguage struct {
+	Unknown     `json:"-"`
+	EnglishName string `json:"english_name"`
+	ISO639_1    string `json:"iso_639_1"`
+	Name        string `json:"name"`
+}
+
+func (m *SpokenLanguage) UnmarshalJSON(data []byte) error {
+	type plain SpokenLanguage
+	return decodeObject(data, (*plain)(m), &m.Unknown)
+}
+
+func (m SpokenLanguage) MarshalJSON() ([]byte, error) {
+	type plain SpokenLanguage
+	return encodeObject(plain(m), m.Unknown)
+}
+
+// ExternalIDs 其他站点的ID (append_to_response=external_ids)
+type ExternalIDs struct {
+	Unknown     `json:"-"`
+	IMDbID      *string `json:"imdb_id"`
+	TVDBID      *int    `json:"tvdb_id"`
+	WikidataID  *string `json:"wikidata_id"`
+	FacebookID  *string `json:"facebook_id"`
+	InstagramID *string `json:"instagram_id"`
+	TwitterID   *string `json:"twitter_id"`
+}
+
+func (m *ExternalIDs) UnmarshalJSON(data []byte) error {
+	type plain ExternalIDs
+	return decodeObject(data, (*plain)(m), &m.Unknown)
+}
+
+func (m ExternalIDs) MarshalJSON() ([]byte, error) {
+	type plain ExternalIDs
+	return encodeObject(plain(m), m.Unknown)
+}
+
+// AlternativeTitles 别名 (append_to_response=alternative_titles)
+// 电影的列表字段为 titles，电视剧为 results
+type AlternativeTitles struct {
+	Unknown `json:"-"`
+	Titles  []AlternativeTitle `json:"titles"`
+	Results []AlternativeTitle `json:"results"`
+}
+
+func (m *AlternativeTitles) UnmarshalJSON(data []byte) error {
+	type plain AlternativeTitles
+	return decodeObject(data, (*plain)(m), &m.Unknown)
+}
+
+func (m AlternativeTitles) MarshalJSON() ([]byte, error) {
+	type plain AlternativeTitles
+	return encodeObject(plain(m), m.Unknown)
+}
+
+// List 返回别名列表，不区分电影和电视剧
+func (a *AlternativeTitles) List() []AlternativeTitle {
+	if a.Titles != nil {
+		return a.Titles
+	}
+	return a.Results
+}
+
+// AlternativeTitle 一个地区的别名
+type AlternativeTitle struct {
+	Unknown   `json:"-"`
+	ISO3166_1 string `json:"iso_3166_1"`
+	Title     string `json:"title"`
+	Type      string `json:"type"`
+}
+
+func (m *AlternativeTitle) UnmarshalJSON(data []byte) error {
+	type plain AlternativeTitle
+	return decodeObject(data, (*plain)(m), &m.Unknown)
+}
+
+func (m AlternativeTitle) MarshalJSON() ([]byte, error) {
+	type plain AlternativeTitle
+	return encodeObject(plain(m), m.Unknown)
+}
+
+// Translations 各语言的翻译 (append_to_response=translations)
+type Translations struct {
+	Unknown      `json:"-"`
+	Translations []Translation `json:"translations"`
+}
+
+func (m *Translations) UnmarshalJSON(data []byte) error {
+	type plain Translations
+	return decodeObject(data, (*plain)(m), &m.Unknown)
+}
+
+func (m Translations) MarshalJSON() ([]byte, error) {
+	type plain Translations
+	return encodeObject(plain(m), m.Unknown)
+}
+
+// Translation 一种语言的翻译
+type Translation struct {
+	Unknown     `json:"-"`
+	ISO3166_1   string          `json:"iso_3166_1"`
+	ISO639_1    string          `json:"iso_639_1"`
+	Name        string          `json:"name"`
+	EnglishName string          `json:"english_name"`
+	Data        TranslationData `json:"data"`
+}
+
+func (m *Translation) UnmarshalJSON(data []byte) error {
+	type plain Translation
+	return decodeObject(data, (*plain)(m), &m.Unknown)
+}
+
+func (m Translation) MarshalJSON() ([]byte, error) {
+	type plain Translation
+	return encodeObject(plain(m), m.Unknown)
+}
+
+// TranslationData 翻译内容，电影使用 title，电视剧、季、集使用 name
+type TranslationData struct {
+	Unknown  `json:"-"`
+	Title    string `json:"title"`
+	Name     string `json:"name"`
+	Overview string `json:"overview"`
+	Tagline  string `json:"tagline"`
+	Homepage string `json:"homepage"`
+	Runtime  int    `json:"runtime"`
+}
+
+func (m *TranslationData) UnmarshalJSON(data []byte) error {
+	type plain TranslationData
+	return decodeObject(data, (*plain)(m), &m.Unknown)
+}
+
+func (m TranslationData) MarshalJSON() ([]byte, error) {
+	type plain TranslationData
+	return encodeObject(plain(m), m.Unknown)
+}
@@ -0,0 +1,176 @@
+package models
+
+// Credits 演职员 (append_to_response=credits)
+type Credits struct {
+	Unknown `json:"-"`
+	Cast    []Cast `json:"cast"`
+	Crew    []Crew `json:"crew"`
+}
+
+func (m *Credits) UnmarshalJSON(data []byte) error {
+	type plain Credits
+	return decodeObject(data, (*plain)(m), &m.Unknown)
+}
+
+func (m Credits) MarshalJSON() ([]byte, error) {
+	type plain Credits
+	return encodeObject(plain(m), m.Unknown)
+}
+
+// Cast 演员
+type Cast struct {
+	Unknown            `json:"-"`
+	Adult              bool    `json:"adult"`
+	Gender             int     `json:"gender"`
+	ID                 int     `json:"id"`
+	KnownForDepartment string  `json:"known_for_department"`
+	Name               string  `json:"name"`
+	OriginalName       string  `json:"original_name"`
+	Popularity         float64 `json:"popularity"`
+	ProfilePath        *string `json:"profile_path"`
+	CastID             int     `json:"cast_id"`
+	Character          string  `json:"character"`
+	CreditID           string  `json:"credit_id"`
+	Order              int     `json:"order"`
+}
+
+func (m *Cast) UnmarshalJSON(data []byte) error {
+	type plain Cast
+	return decodeObject(data, (*plain)(m), &m.Unknown)
+}
+
+func (m Cast) MarshalJSON() ([]byte, error) {
+	type plain Cast
+	return encodeObject(plain(m), m.Unknown)
+}
+
+// Crew 职员
+type Crew struct {
+	Unknown            `json:"-"`
+	Adult              bool    `json:"adult"`
+	Gender             int     `json:"gender"`
+	ID                 int     `json:"id"`
+	KnownForDepartment string  `json:"known_for_department"`
+	Name               string  `json:"name"`
+	OriginalName       string  `json:"original_name"`
+	Popularity         float64 `json:"popularity"`
+	ProfilePath        *string `json:"profile_path"`
+	CreditID           string  `json:"credit_id"`
+	Department         string  `json:"department"`
+	Job                string  `json:"job"`
+}
+
+func (m *Crew) UnmarshalJSON(data []byte) error {
+	type plain Crew
+	return decodeObject(data, (*plain)(m), &m.Unknown)
+}
+
+func (m Crew) MarshalJSON() ([]byte, error) {
+	type plain Crew
+	return encodeObject(plain(m), m.Unknown)
+}
+
+// AggregateCredits 电视剧全部季的演职员汇总 (append_to_response=aggregate_credits)
+type AggregateCredits struct {
+	Unknown `json:"-"`
+	Cast    []AggregateCast `json:"cast"`
+	Crew    []AggregateCrew `json:"crew"`
+}
+
+func (m *AggregateCredits) UnmarshalJSON(data []byte) error {
+	type plain AggregateCredits
+	return decodeObject(data, (*plain)(m), &m.Unknown)
+}
+
+func (m AggregateCredits) MarshalJSON() ([]byte, error) {
+	type plain AggregateCredits
+	return encodeObject(plain(m), m.Unknown)
+}
+
+// AggregateCast 汇总的演员，一个演员可以有多个角色
+type AggregateCast struct {
+	Unknown            `json:"-"`
+	Adult              bool    `json:"adult"`
+	Gender             int     `json:"gender"`
+	ID                 int     `json:"id"`
+	KnownForDepartment string  `json:"known_for_department"`
+	Name               string  `json:"name"`
+	OriginalName       string  `json:"original_name"`
+	Popularity         float64 `json:"popularity"`
+	ProfilePath        *string `json:"profile_path"`
+	Roles              []Role  `json:"roles"`
+	TotalEpisodeCount  int     `json:"total_episode_count"`
+	Order              int     `json:"order"`
+}
+
+func (m *AggregateCast) UnmarshalJSON(data []byte) error {
+	type plain AggregateCast
+	return decodeObject(data, (*plain)(m), &m.Unknown)
+}
+
+func (m AggregateCast) MarshalJSON() ([]byte, error) {
+	type plain AggregateCast
+	return encodeObject(plain(m), m.Unknown)
+}
+
+// Role 演员在电视剧中的一个角色
+type Role struct {
+	Unknown      `json:"-"`
+	CreditID     string `json:"credit_id"`
+	Character    string `json:"character"`
+	EpisodeCount int    `json:"episode_count"`
+}
+
+func (m *Role) UnmarshalJSON(data []byte) error {
+	type plain Role
+	return decodeObject(data, (*plain)(m), &m.Unknown)
+}
+
+func (m Role) MarshalJSON() ([]byte, error) {
+	type plain Role
+	return encodeObject(plain(m), m.Unknown)
+}
+
+// AggregateCrew 汇总的职员，一个职员可以有多个职位
+type AggregateCrew struct {
+	Unknown            `json:"-"`
+	Adult              bool    `json:"adult"`
+	Gender             int     `json:"gender"`
+	ID                 int     `json:"id"`
+	KnownForDepartment string  `json:"known_for_department"`
+	Name               string  `json:"name"`
+	OriginalName       string  `json:"original_name"`
+	Popularity         float64 `json:"popularity"`
+	ProfilePath        *string `json:"profile_path"`
+	Jobs               []Job   `json:"jobs"`
+	Department         string  `json:"department"`
+	TotalEpisodeCount  int     `json:"total_episode_count"`
+}
+
+func (m *AggregateCrew) UnmarshalJSON(data []byte) error {
+	type plain AggregateCrew
+	return decodeObject(data, (*plain)(m), &m.Unknown)
+}
+
+func (m AggregateCrew) MarshalJSON() ([]byte, error) {
+	type plain AggregateCrew
+	return encodeObject(plain(m), m.Unknown)
+}
+
+// Job 职员在电视剧中的一个职位
+type Job struct {
+	Unknown      `json:"-"`
+	CreditID     string `json:"credit_id"`
+	Job          string `json:"job"`
+	EpisodeCount int    `json:"episode_count"`
+}
+
+func (m *Job) UnmarshalJSON(data []byte) error {
+	type plain Job
+	return decodeObject(data, (*plain)(m), &m.Unknown)
+}
+
+func (m Job) MarshalJSON() ([]byte, error) {
+	type plain Job
+	return encodeObject(plain(m), m.Unknown)
+}
@@ -0,0 +1,75 @@
+package models
+
+// MovieDetails 电影详情 (movie/{id}/details.json)
+type MovieDetails struct {
+	Unknown             `json:"-"`
+	Adult               bool                `json:"adult"`
+	BackdropPath        *string             `json:"backdrop_path"`
+	BelongsToCollection *Collection         `json:"belongs_to_collection"`
+	Budget              int64               `json:"budget"`
+	Genres              []Genre             `json:"genres"`
+	Homepage            string              `json:"homepage"`
+	ID                  int                 `json:"id"`
+	IMDbID              *string             `json:"imdb_id"`
+	OriginCountry       []string            `json:"origin_country"`
+	OriginalLanguage    string              `json:"original_language"`
+	OriginalTitle       string              `json:"original_title"`
+	Overview            string              `json:"overview"`
+	Popularity          float64             `json:"popularity"`
+	PosterPath          *string             `json:"poster_path"`
+	ProductionCompanies []ProductionCompany `json:"production_companies"`
+	ProductionCountries []ProductionCountry `json:"production_countries"`
+	ReleaseDate         Date                `json:"release_date"`
+	Revenue             int64               `json:"revenue"`
+	Runtime             int                 `json:"runtime"`
+	SpokenLanguages     []SpokenLanguage    `json:"spoken_languages"`
+	Status              string              `json:"status"`
+	Tagline             string              `json:"tagline"`
+	Title               string              `json:"title"`
+	Video               bool                `json:"video"`
+	VoteAverage         float64             `json:"vote_average"`
+	VoteCount           int                 `json:"vote_count"`
+
+	// append_to_response 附加的数据
+	Credits           *Credits           `json:"credits"`
+	AlternativeTitles *AlternativeTitles `json:"alternative_titles"`
+	Translations      *Translations      `json:"translations"`
+	ExternalIDs       *ExternalIDs       `json:"external_ids"`
+}
+
+func (m *MovieDetails) UnmarshalJSON(data []byte) error {
+	type plain MovieDetails
+	return decodeObject(data, (*plain)(m), &m.Unknown)
+}
+
+func (m MovieDetails) MarshalJSON() ([]byte, error) {
+	type plain MovieDetails
+	return encodeObject(plain(m), m.Unknown)
+}
+
+// DisplayTitle 返回标题，没有翻译后的标题时使用原始标题
+func (m *MovieDetails) DisplayTitle() string {
+	if m.Title != "" {
+		return m.Title
+	}
+	return m.OriginalTitle
+}
+
+// Collection 电影所属的系列
+type Collection struct {
+	Unknown      `json:"-"`
+	ID           int     `json:"id"`
+	Name         string  `json:"name"`
+	PosterPath   *string `json:"poster_path"`
+	BackdropPath *string `json:"backdrop_path"`
+}
+
+func (m *Collection) UnmarshalJSON(data []byte) error {
+	type plain Collection
+	return decodeObject(data, (*plain)(m), &m.Unknown)
+}
+
+func (m Collection) MarshalJSON() ([]byte, error) {
+	type plain Collection
+	return encodeObject(plain(m), m.Unknown)
+}
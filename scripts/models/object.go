@@ -0,0 +1,172 @@
+// Package models TMDB数据文件的结构体定义
+//
+// 所有模型都嵌入 Unknown，解码时记录结构体中没有定义的字段以及原始数据中缺失或为 null 的字段，
+// 编码时按原样还原，因此读取后再写回不会丢失或改变任何数据。
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Unknown 保存解码时结构体无法表示的信息，嵌入到每个模型中使用
+type Unknown struct {
+	extra  map[string]json.RawMessage // 结构体中没有定义的字段
+	absent map[string]bool            // 原始数据中不存在的已定义字段
+	null   map[string]bool            // 原始数据中为 null 的已定义字段
+}
+
+// Extra 返回结构体中没有定义的字段
+func (u Unknown) Extra() map[string]json.RawMessage {
+	return u.extra
+}
+
+// SetExtra 设置结构体中没有定义的字段，value 为 nil 时删除该字段
+func (u *Unknown) SetExtra(key string, value json.RawMessage) {
+	if value == nil {
+		delete(u.extra, key)
+		return
+	}
+	if u.extra == nil {
+		u.extra = make(map[string]json.RawMessage)
+	}
+	u.extra[key] = value
+}
+
+// decodeObject 将JSON对象解码到 v (不带 UnmarshalJSON 方法的同构类型)，并记录 Unknown 信息
+func decodeObject(data []byte, v interface{}, u *Unknown) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if raw == nil {
+		// null，与 encoding/json 的约定一致不做任何修改
+		return nil
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+
+	*u = Unknown{}
+	known := jsonFields(reflect.TypeOf(v).Elem())
+	for key, value := range raw {
+		switch {
+		case !known[key]:
+			u.SetExtra(key, value)
+		case bytes.Equal(bytes.TrimSpace(value), []byte("null")):
+			if u.null == nil {
+				u.null = make(map[string]bool)
+			}
+			u.null[key] = true
+		}
+	}
+	for key := range known {
+		if _, ok := raw[key]; !ok {
+			if u.absent == nil {
+				u.absent = make(map[string]bool)
+			}
+			u.absent[key] = true
+		}
+	}
+	return nil
+}
+
+// encodeObject 将 v (不带 MarshalJSON 方法的同构类型) 编码为JSON对象，并还原 Unknown 信息
+// 原始数据中不存在或为 null 的字段，只要值仍为零值就按原样省略或写为 null
+func encodeObject(v interface{}, u Unknown) ([]byte, error) {
+	data, err := marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	for key, value := range fields {
+		if !isZeroJSON(value) {
+			continue
+		}
+		if u.absent[key] {
+			delete(fields, key)
+		} else if u.null[key] {
+			fields[key] = json.RawMessage("null")
+		}
+	}
+	for key, value := range u.extra {
+		fields[key] = value
+	}
+	return marshal(fields)
+}
+
+// marshal 编码JSON，不转义 HTML 字符，与 tmdb_manager 保存文件时的设置一致
+func marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// isZeroJSON 判断编码结果是否为零值
+func isZeroJSON(value json.RawMessage) bool {
+	switch string(value) {
+	case `""`, "0", "false", "null":
+		return true
+	}
+	return false
+}
+
+var fieldCache sync.Map // reflect.Type -> map[string]bool
+
+// jsonFields 返回结构体中定义的全部JSON字段名
+func jsonFields(t reflect.Type) map[string]bool {
+	if cached, ok := fieldCache.Load(t); ok {
+		return cached.(map[string]bool)
+	}
+
+	fields := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = true
+	}
+	fieldCache.Store(t, fields)
+	return fields
+}
+
+// FromMap 将通用的 map 数据 (如 tmdb_manager 获取的响应) 转换为模型
+func FromMap(data map[string]interface{}, v interface{}) error {
+	b, err := marshal(data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// ToMap 将模型转换为通用的 map 数据，用于保存或与其他数据合并
+func ToMap(v interface{}) (map[string]interface{}, error) {
+	b, err := marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal(b, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
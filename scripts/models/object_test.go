@@ -0,0 +1,131 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// encodeFile 按 tmdb_manager 保存文件的格式编码
+func encodeFile(t *testing.T, v interface{}) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// decodeFixture 将 testdata 中的文件解码到 v，返回文件内容
+func decodeFixture(t *testing.T, name string, v interface{}) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatalf("解码 %s 失败: %v", name, err)
+	}
+	return data
+}
+
+// testdata 中的文件来自 tmdb_config，加入了未定义的字段、为 null 的字段，并删除了部分字段
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		v    interface{}
+	}{
+		{"movie_details.json", &MovieDetails{}},
+		{"tv_details.json", &TVDetails{}},
+		{"release_dates.json", &ReleaseDates{}},
+		{"content_ratings.json", &ContentRatings{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := decodeFixture(t, tt.name, tt.v)
+			if got := encodeFile(t, tt.v); !bytes.Equal(got, data) {
+				t.Errorf("重新编码的结果与原文件不一致:\n%s", got)
+			}
+
+			// 经过 map 转换后仍保持一致
+			m, err := ToMap(tt.v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := encodeFile(t, m); !bytes.Equal(got, data) {
+				t.Errorf("ToMap 的结果与原文件不一致:\n%s", got)
+			}
+		})
+	}
+}
+
+func TestRoundTripModifiedFields(t *testing.T) {
+	var details MovieDetails
+	decodeFixture(t, "movie_details.json", &details)
+
+	if details.Extra()["video_sources"] == nil || details.Credits.Cast[0].Extra()["gender_label"] == nil {
+		t.Fatalf("未定义的字段没有保留: %v", details.Extra())
+	}
+
+	details.Runtime = 0      // 原有的字段改为零值
+	details.Tagline = "新的标语" // 缺失的字段设置了值
+	details.Homepage = ""    // 原值为空字符串
+	details.SetExtra("video_sources", nil)
+
+	m, err := ToMap(&details)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]interface{}{
+		"runtime":               float64(0),
+		"tagline":               "新的标语",
+		"homepage":              "",
+		"belongs_to_collection": nil,
+	} {
+		got, ok := m[key]
+		if !ok || got != want {
+			t.Errorf("%s = %#v (存在: %v), 期望 %#v", key, got, ok, want)
+		}
+	}
+	if _, ok := m["video_sources"]; ok {
+		t.Error("SetExtra(nil) 删除的字段仍然存在")
+	}
+
+	// 缺失的字段恢复为零值后再次省略
+	details.Tagline = ""
+	if m, err = ToMap(&details); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m["tagline"]; ok {
+		t.Error("原文件中缺失、恢复为零值的 tagline 不应写入")
+	}
+
+	// 原值为 null 的字段设置值后写入该值，恢复为零值后再次写为 null
+	var releaseDates ReleaseDates
+	decodeFixture(t, "release_dates.json", &releaseDates)
+	release := &releaseDates.Results[0].ReleaseDates[0]
+	release.Note = "首映"
+	if got := encodeFile(t, release); !strings.Contains(string(got), `"note": "首映"`) {
+		t.Errorf("设置的 note 没有写入:\n%s", got)
+	}
+	release.Note = ""
+	if got := encodeFile(t, release); !strings.Contains(string(got), `"note": null`) {
+		t.Errorf("恢复为零值的 note 应写为 null:\n%s", got)
+	}
+}
+
+func TestDecodeNullObject(t *testing.T) {
+	details := MovieDetails{ID: 1}
+	if err := json.Unmarshal([]byte("null"), &details); err != nil {
+		t.Fatal(err)
+	}
+	if details.ID != 1 {
+		t.Errorf("解码 null 不应修改原有的值, ID = %d", details.ID)
+	}
+}
@@ -0,0 +1,91 @@
+package models
+
+// ReleaseDates 电影各地区的发行日期和分级 (release_dates.json)
+type ReleaseDates struct {
+	Unknown `json:"-"`
+	ID      int                   `json:"id"`
+	Results []CountryReleaseDates `json:"results"`
+}
+
+func (m *ReleaseDates) UnmarshalJSON(data []byte) error {
+	type plain ReleaseDates
+	return decodeObject(data, (*plain)(m), &m.Unknown)
+}
+
+func (m ReleaseDates) MarshalJSON() ([]byte, error) {
+	type plain ReleaseDates
+	return encodeObject(plain(m), m.Unknown)
+}
+
+// CountryReleaseDates 一个地区的发行信息
+type CountryReleaseDates struct {
+	Unknown      `json:"-"`
+	ISO3166_1    string        `json:"iso_3166_1"`
+	ReleaseDates []ReleaseDate `json:"release_dates"`
+}
+
+func (m *CountryReleaseDates) UnmarshalJSON(data []byte) error {
+	type plain CountryReleaseDates
+	return decodeObject(data, (*plain)(m), &m.Unknown)
+}
+
+func (m CountryReleaseDates) MarshalJSON() ([]byte, error) {
+	type plain CountryReleaseDates
+	return encodeObject(plain(m), m.Unknown)
+}
+
+// ReleaseDate 一次发行
+type ReleaseDate struct {
+	Unknown       `json:"-"`
+	Certification string   `json:"certification"`
+	Descriptors   []string `json:"descriptors"`
+	ISO639_1      string   `json:"iso_639_1"`
+	Note          string   `json:"note"`
+	ReleaseDate   Date     `json:"release_date"`
+	Type          int      `json:"type"`
+}
+
+func (m *ReleaseDate) UnmarshalJSON(data []byte) error {
+	type plain ReleaseDate
+	return decodeObject(data, (*plain)(m), &m.Unknown)
+}
+
+func (m ReleaseDate) MarshalJSON() ([]byte, error) {
+	type plain ReleaseDate
+	return encodeObject(plain(m), m.Unknown)
+}
+
+// ContentRatings 电视剧各地区的内容分级 (content_ratings.json)
+type ContentRatings struct {
+	Unknown `json:"-"`
+	ID      int             `json:"id"`
+	Results []ContentRating `json:"results"`
+}
+
+func (m *ContentRatings) UnmarshalJSON(data []byte) error {
+	type plain ContentRatings
+	return decodeObject(data, (*plain)(m), &m.Unknown)
+}
+
+func (m ContentRatings) MarshalJSON() ([]byte, error) {
+	type plain ContentRatings
+	return encodeObject(plain(m), m.Unknown)
+}
+
+// ContentRating 一个地区的内容分级
+type ContentRating struct {
+	Unknown     `json:"-"`
+	Descriptors []string `json:"descriptors"`
+	ISO3166_1   string   `json:"iso_3166_1"`
+	Rating      string   `json:"rating"`
+}
+
+func (m *ContentRating) UnmarshalJSON(data []byte) error {
+	type plain ContentRating
+	return decodeObject(data, (*plain)(m), &m.Unknown)
+}
+
+func (m ContentRating) MarshalJSON() ([]byte, error) {
+	type plain ContentRating
+	return encodeObject(plain(m), m.Unknown)
+}
@@ -0,0 +1,64 @@
+package models
+
+// Season 电视剧单季详情 (tv/{id}/season/{n}/details.json)
+type Season struct {
+	Unknown      `json:"-"`
+	AirDate      Date      `json:"air_date"`
+	Episodes     []Episode `json:"episodes"`
+	ID           int       `json:"id"`
+	Name         string    `json:"name"`
+	Overview     string    `json:"overview"`
+	PosterPath   *string   `json:"poster_path"`
+	SeasonNumber int       `json:"season_number"`
+	VoteAverage  float64   `json:"vote_average"`
+
+	// append_to_response 附加的数据
+	Credits      *Credits      `json:"credits"`
+	Translations *Translations `json:"translations"`
+	ExternalIDs  *ExternalIDs  `json:"external_ids"`
+}
+
+func (m *Season) UnmarshalJSON(data []byte) error {
+	type plain Season
+	return decodeObject(data, (*plain)(m), &m.Unknown)
+}
+
+func (m Season) MarshalJSON() ([]byte, error) {
+	type plain Season
+	return encodeObject(plain(m), m.Unknown)
+}
+
+// Episode 电视剧单集详情 (tv/{id}/season/{n}/episode/{m}/details.json)，也用于单季详情中的集列表
+type Episode struct {
+	Unknown        `json:"-"`
+	AirDate        Date    `json:"air_date"`
+	EpisodeNumber  int     `json:"episode_number"`
+	EpisodeType    string  `json:"episode_type"`
+	ID             int     `json:"id"`
+	Name           string  `json:"name"`
+	Overview       string  `json:"overview"`
+	ProductionCode string  `json:"production_code"`
+	Runtime        *int    `json:"runtime"`
+	SeasonNumber   int     `json:"season_number"`
+	ShowID         int     `json:"show_id"`
+	StillPath      *string `json:"still_path"`
+	VoteAverage    float64 `json:"vote_average"`
+	VoteCount      int     `json:"vote_count"`
+	Crew           []Crew  `json:"crew"`
+	GuestStars     []Cast  `json:"guest_stars"`
+
+	// append_to_response 附加的数据，只在单集详情中出现
+	Credits      *Credits      `json:"credits"`
+	Translations *Translations `json:"translations"`
+	ExternalIDs  *ExternalIDs  `json:"external_ids"`
+}
+
+func (m *Episode) UnmarshalJSON(data []byte) error {
+	type plain Episode
+	return decodeObject(data, (*plain)(m), &m.Unknown)
+}
+
+func (m Episode) MarshalJSON() ([]byte, error) {
+	type plain Episode
+	return encodeObject(plain(m), m.Unknown)
+}
@@ -0,0 +1,154 @@
+package models
+
+// TVDetails 电视剧详情 (tv/{id}/details.json)
+type TVDetails struct {
+	Unknown             `json:"-"`
+	Adult               bool                `json:"adult"`
+	BackdropPath        *string             `json:"backdrop_path"`
+	CreatedBy           []Creator           `json:"created_by"`
+	EpisodeRunTime      []int               `json:"episode_run_time"`
+	FirstAirDate        Date                `json:"first_air_date"`
+	Genres              []Genre             `json:"genres"`
+	Homepage            string              `json:"homepage"`
+	ID                  int                 `json:"id"`
+	InProduction        bool                `json:"in_production"`
+	Languages           []string            `json:"languages"`
+	LastAirDate         Date                `json:"last_air_date"`
+	LastEpisodeToAir    *EpisodeSummary     `json:"last_episode_to_air"`
+	Name                string              `json:"name"`
+	Networks            []Network           `json:"networks"`
+	NextEpisodeToAir    *EpisodeSummary     `json:"next_episode_to_air"`
+	NumberOfEpisodes    int                 `json:"number_of_episodes"`
+	NumberOfSeasons     int                 `json:"number_of_seasons"`
+	OriginCountry       []string            `json:"origin_country"`
+	OriginalLanguage    string              `json:"original_language"`
+	OriginalName        string              `json:"original_name"`
+	Overview            string              `json:"overview"`
+	Popularity          float64             `json:"popularity"`
+	PosterPath          *string             `json:"poster_path"`
+	ProductionCompanies []ProductionCompany `json:"production_companies"`
+	ProductionCountries []ProductionCountry `json:"production_countries"`
+	Seasons             []SeasonSummary     `json:"seasons"`
+	SpokenLanguages     []SpokenLanguage    `json:"spoken_languages"`
+	Status              string              `json:"status"`
+	Tagline             string              `json:"tagline"`
+	Type                string              `json:"type"`
+	VoteAverage         float64             `json:"vote_average"`
+	VoteCount           int                 `json:"vote_count"`
+
+	// append_to_response 附加的数据
+	Credits           *Credits           `json:"credits"`
+	AggregateCredits  *AggregateCredits  `json:"aggregate_credits"`
+	AlternativeTitles *AlternativeTitles `json:"alternative_titles"`
+	Translations      *Translations      `json:"translations"`
+	ExternalIDs       *ExternalIDs       `json:"external_ids"`
+}
+
+func (m *TVDetails) UnmarshalJSON(data []byte) error {
+	type plain TVDetails
+	return decodeObject(data, (*plain)(m), &m.Unknown)
+}
+
+func (m TVDetails) MarshalJSON() ([]byte, error) {
+	type plain TVDetails
+	return encodeObject(plain(m), m.Unknown)
+}
+
+// DisplayTitle 返回标题，没有翻译后的标题时使用原始标题
+func (m *TVDetails) DisplayTitle() string {
+	if m.Name != "" {
+		return m.Name
+	}
+	return m.OriginalName
+}
+
+// Creator 电视剧的创作者
+type Creator struct {
+	Unknown      `json:"-"`
+	ID           int     `json:"id"`
+	CreditID     string  `json:"credit_id"`
+	Name         string  `json:"name"`
+	OriginalName string  `json:"original_name"`
+	Gender       int     `json:"gender"`
+	ProfilePath  *string `json:"profile_path"`
+}
+
+func (m *Creator) UnmarshalJSON(data []byte) error {
+	type plain Creator
+	return decodeObject(data, (*plain)(m), &m.Unknown)
+}
+
+func (m Creator) MarshalJSON() ([]byte, error) {
+	type plain Creator
+	return encodeObject(plain(m), m.Unknown)
+}
+
+// Network 播出平台
+type Network struct {
+	Unknown       `json:"-"`
+	ID            int     `json:"id"`
+	LogoPath      *string `json:"logo_path"`
+	Name          string  `json:"name"`
+	OriginCountry string  `json:"origin_country"`
+}
+
+func (m *Network) UnmarshalJSON(data []byte) error {
+	type plain Network
+	return decodeObject(data, (*plain)(m), &m.Unknown)
+}
+
+func (m Network) MarshalJSON() ([]byte, error) {
+	type plain Network
+	return encodeObject(plain(m), m.Unknown)
+}
+
+// SeasonSummary 电视剧详情中的季概要
+type SeasonSummary struct {
+	Unknown      `json:"-"`
+	AirDate      Date    `json:"air_date"`
+	EpisodeCount int     `json:"episode_count"`
+	ID           int     `json:"id"`
+	Name         string  `json:"name"`
+	Overview     string  `json:"overview"`
+	PosterPath   *string `json:"poster_path"`
+	SeasonNumber int     `json:"season_number"`
+	VoteAverage  float64 `json:"vote_average"`
+}
+
+func (m *SeasonSummary) UnmarshalJSON(data []byte) error {
+	type plain SeasonSummary
+	return decodeObject(data, (*plain)(m), &m.Unknown)
+}
+
+func (m SeasonSummary) MarshalJSON() ([]byte, error) {
+	type plain SeasonSummary
+	return encodeObject(plain(m), m.Unknown)
+}
+
+// EpisodeSummary 电视剧详情中最近播出/即将播出的一集
+type EpisodeSummary struct {
+	Unknown        `json:"-"`
+	ID             int     `json:"id"`
+	Name           string  `json:"name"`
+	Overview       string  `json:"overview"`
+	VoteAverage    float64 `json:"vote_average"`
+	VoteCount      int     `json:"vote_count"`
+	AirDate        Date    `json:"air_date"`
+	EpisodeNumber  int     `json:"episode_number"`
+	EpisodeType    string  `json:"episode_type"`
+	ProductionCode string  `json:"production_code"`
+	Runtime        *int    `json:"runtime"`
+	SeasonNumber   int     `json:"season_number"`
+	ShowID         int     `json:"show_id"`
+	StillPath      *string `json:"still_path"`
+}
+
+func (m *EpisodeSummary) UnmarshalJSON(data []byte) error {
+	type plain EpisodeSummary
+	return decodeObject(data, (*plain)(m), &m.Unknown)
+}
+
+func (m EpisodeSummary) MarshalJSON() ([]byte, error) {
+	type plain EpisodeSummary
+	return encodeObject(plain(m), m.Unknown)
+}
@@ -0,0 +1,489 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"tmdb-manager/models"
+)
+
+// NFO 导出的默认值，目录相对于 scripts/cli 目录
+const (
+	defaultNFODir     = "../tmdb_nfo"
+	defaultNFOCountry = "CN"
+	fallbackCountry   = "US" // 指定地区没有分级时使用美国的分级
+	tmdbImageBaseURL  = "https://image.tmdb.org/t/p/original"
+)
+
+// nfoHeader NFO 文件的XML声明
+const nfoHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n"
+
+// nfoRating 评分
+type nfoRating struct {
+	Name    string  `xml:"name,attr"`
+	Max     int     `xml:"max,attr"`
+	Default bool    `xml:"default,attr"`
+	Value   float64 `xml:"value"`
+	Votes   int     `xml:"votes,omitempty"`
+}
+
+// nfoRatings 评分列表，没有评分时不输出
+type nfoRatings struct {
+	Ratings []nfoRating `xml:"rating"`
+}
+
+// nfoUniqueID 其他站点的ID，type 为 tmdb、imdb、tvdb、wikidata
+type nfoUniqueID struct {
+	Type    string `xml:"type,attr"`
+	Default bool   `xml:"default,attr,omitempty"`
+	Value   string `xml:",chardata"`
+}
+
+// nfoActor 演员
+type nfoActor struct {
+	Name  string `xml:"name"`
+	Role  string `xml:"role,omitempty"`
+	Order int    `xml:"order"`
+	Thumb string `xml:"thumb,omitempty"`
+}
+
+// nfoSet 电影所属的系列
+type nfoSet struct {
+	Name string `xml:"name"`
+}
+
+// nfoNamedSeason 电视剧中各季的名称
+type nfoNamedSeason struct {
+	Number int    `xml:"number,attr"`
+	Name   string `xml:",chardata"`
+}
+
+// nfoMovie movie.nfo
+type nfoMovie struct {
+	XMLName       xml.Name      `xml:"movie"`
+	Title         string        `xml:"title"`
+	OriginalTitle string        `xml:"originaltitle,omitempty"`
+	Tagline       string        `xml:"tagline,omitempty"`
+	Plot          string        `xml:"plot,omitempty"`
+	Runtime       int           `xml:"runtime,omitempty"`
+	MPAA          string        `xml:"mpaa,omitempty"`
+	Premiered     string        `xml:"premiered,omitempty"`
+	Year          string        `xml:"year,omitempty"`
+	Ratings       *nfoRatings   `xml:"ratings"`
+	UniqueIDs     []nfoUniqueID `xml:"uniqueid"`
+	Genres        []string      `xml:"genre"`
+	Countries     []string      `xml:"country"`
+	Studios       []string      `xml:"studio"`
+	Set           *nfoSet       `xml:"set"`
+	Directors     []string      `xml:"director"`
+	Credits       []string      `xml:"credits"`
+	Actors        []nfoActor    `xml:"actor"`
+}
+
+// nfoTVShow tvshow.nfo
+type nfoTVShow struct {
+	XMLName       xml.Name         `xml:"tvshow"`
+	Title         string           `xml:"title"`
+	OriginalTitle string           `xml:"originaltitle,omitempty"`
+	Tagline       string           `xml:"tagline,omitempty"`
+	Plot          string           `xml:"plot,omitempty"`
+	MPAA          string           `xml:"mpaa,omitempty"`
+	Premiered     string           `xml:"premiered,omitempty"`
+	Year          string           `xml:"year,omitempty"`
+	Status        string           `xml:"status,omitempty"`
+	Ratings       *nfoRatings      `xml:"ratings"`
+	UniqueIDs     []nfoUniqueID    `xml:"uniqueid"`
+	Genres        []string         `xml:"genre"`
+	Studios       []string         `xml:"studio"`
+	NamedSeasons  []nfoNamedSeason `xml:"namedseason"`
+	Credits       []string         `xml:"credits"`
+	Actors        []nfoActor       `xml:"actor"`
+}
+
+// nfoSeason season.nfo
+type nfoSeason struct {
+	XMLName      xml.Name      `xml:"season"`
+	Title        string        `xml:"title"`
+	Plot         string        `xml:"plot,omitempty"`
+	Premiered    string        `xml:"premiered,omitempty"`
+	SeasonNumber int           `xml:"seasonnumber"`
+	UniqueIDs    []nfoUniqueID `xml:"uniqueid"`
+}
+
+// nfoEpisode 单集的 NFO
+type nfoEpisode struct {
+	XMLName   xml.Name      `xml:"episodedetails"`
+	Title     string        `xml:"title"`
+	ShowTitle string        `xml:"showtitle"`
+	Season    int           `xml:"season"`
+	Episode   int           `xml:"episode"`
+	Plot      string        `xml:"plot,omitempty"`
+	Runtime   int           `xml:"runtime,omitempty"`
+	Aired     string        `xml:"aired,omitempty"`
+	Ratings   *nfoRatings   `xml:"ratings"`
+	UniqueIDs []nfoUniqueID `xml:"uniqueid"`
+	Directors []string      `xml:"director"`
+	Credits   []string      `xml:"credits"`
+	Actors    []nfoActor    `xml:"actor"`
+}
+
+// nfoExporter 把 tmdb_config 中维护的数据导出为 Kodi/Jellyfin 的 NFO 文件
+type nfoExporter struct {
+	local   *TMDBFetcher // 只用于读取本地数据，不需要API Key
+	destDir string
+	country string // 分级使用的地区
+}
+
+// exportMedia 导出一个条目，返回写入的文件数
+func (e *nfoExporter) exportMedia(key mediaKey) (int, error) {
+	if key.mediaType == "movie" {
+		return e.exportMovie(key.mediaID)
+	}
+	return e.exportTV(key.mediaID)
+}
+
+// loadModel 读取维护的数据文件并转换为结构体，文件不存在时返回 false
+func (e *nfoExporter) loadModel(key mediaKey, relPath string, v interface{}) (bool, error) {
+	data, err := e.local.loadCuratedFile(key.mediaType, key.mediaID, relPath)
+	if err != nil || data == nil {
+		return false, err
+	}
+	if err := models.FromMap(data, v); err != nil {
+		return false, fmt.Errorf("%s/%s: %v", key, relPath, err)
+	}
+	return true, nil
+}
+
+// exportMovie 导出 movie/{id}/movie.nfo
+func (e *nfoExporter) exportMovie(movieID string) (int, error) {
+	key := mediaKey{mediaType: "movie", mediaID: movieID}
+	var movie models.MovieDetails
+	if ok, err := e.loadModel(key, "details.json", &movie); err != nil || !ok {
+		return 0, missingDetails(key, err)
+	}
+	var releaseDates models.ReleaseDates
+	if _, err := e.loadModel(key, "release_dates.json", &releaseDates); err != nil {
+		return 0, err
+	}
+
+	nfo := nfoMovie{
+		Title:     movie.DisplayTitle(),
+		Tagline:   movie.Tagline,
+		Plot:      movie.Overview,
+		Runtime:   movie.Runtime,
+		MPAA:      movieCertification(releaseDates, e.country),
+		Premiered: string(movie.ReleaseDate),
+		Year:      movie.ReleaseDate.Year(),
+		Ratings:   tmdbRating(movie.VoteAverage, movie.VoteCount),
+		UniqueIDs: uniqueIDs(movie.ID, movie.ExternalIDs, movie.IMDbID),
+	}
+	if movie.OriginalTitle != nfo.Title {
+		nfo.OriginalTitle = movie.OriginalTitle
+	}
+	for _, genre := range movie.Genres {
+		nfo.Genres = append(nfo.Genres, genre.Name)
+	}
+	for _, country := range movie.ProductionCountries {
+		nfo.Countries = append(nfo.Countries, country.Name)
+	}
+	for _, company := range movie.ProductionCompanies {
+		nfo.Studios = append(nfo.Studios, company.Name)
+	}
+	if movie.BelongsToCollection != nil {
+		nfo.Set = &nfoSet{Name: movie.BelongsToCollection.Name}
+	}
+	nfo.Directors, nfo.Credits, nfo.Actors = creditsInfo(movie.Credits)
+
+	if err := writeNFO(filepath.Join(e.destDir, "movie", movieID, "movie.nfo"), nfo); err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
+
+// exportTV 导出 tv/{id}/tvshow.nfo，有单季数据时同时导出 Season N/season.nfo 和每集的 SxxEyy.nfo
+func (e *nfoExporter) exportTV(tvID string) (int, error) {
+	key := mediaKey{mediaType: "tv", mediaID: tvID}
+	var tv models.TVDetails
+	if ok, err := e.loadModel(key, "details.json", &tv); err != nil || !ok {
+		return 0, missingDetails(key, err)
+	}
+	var ratings models.ContentRatings
+	if _, err := e.loadModel(key, "content_ratings.json", &ratings); err != nil {
+		return 0, err
+	}
+
+	nfo := nfoTVShow{
+		Title:     tv.DisplayTitle(),
+		Tagline:   tv.Tagline,
+		Plot:      tv.Overview,
+		MPAA:      tvCertification(ratings, e.country),
+		Premiered: string(tv.FirstAirDate),
+		Year:      tv.FirstAirDate.Year(),
+		Status:    tv.Status,
+		Ratings:   tmdbRating(tv.VoteAverage, tv.VoteCount),
+		UniqueIDs: uniqueIDs(tv.ID, tv.ExternalIDs, nil),
+	}
+	if tv.OriginalName != nfo.Title {
+		nfo.OriginalTitle = tv.OriginalName
+	}
+	for _, genre := range tv.Genres {
+		nfo.Genres = append(nfo.Genres, genre.Name)
+	}
+	for _, network := range tv.Networks {
+		nfo.Studios = append(nfo.Studios, network.Name)
+	}
+	for _, season := range tv.Seasons {
+		nfo.NamedSeasons = append(nfo.NamedSeasons, nfoNamedSeason{Number: season.SeasonNumber, Name: season.Name})
+	}
+	for _, creator := range tv.CreatedBy {
+		nfo.Credits = append(nfo.Credits, creator.Name)
+	}
+	if tv.AggregateCredits != nil {
+		nfo.Actors = aggregateActors(tv.AggregateCredits.Cast)
+	} else {
+		_, _, nfo.Actors = creditsInfo(tv.Credits)
+	}
+
+	tvDir := filepath.Join(e.destDir, "tv", tvID)
+	if err := writeNFO(filepath.Join(tvDir, "tvshow.nfo"), nfo); err != nil {
+		return 0, err
+	}
+	count := 1
+	for _, summary := range tv.Seasons {
+		n, err := e.exportSeason(key, tvDir, nfo.Title, summary.SeasonNumber)
+		if err != nil {
+			return count, err
+		}
+		count += n
+	}
+	return count, nil
+}
+
+// exportSeason 导出一季的 season.nfo 和每集的 NFO，没有单季数据时跳过
+func (e *nfoExporter) exportSeason(key mediaKey, tvDir, showTitle string, seasonNumber int) (int, error) {
+	seasonPath := path.Join("season", strconv.Itoa(seasonNumber))
+	var season models.Season
+	ok, err := e.loadModel(key, path.Join(seasonPath, "details.json"), &season)
+	if err != nil || !ok {
+		return 0, err
+	}
+
+	// Kodi 和 Jellyfin 默认的季目录名
+	seasonDir := filepath.Join(tvDir, fmt.Sprintf("Season %d", seasonNumber))
+	nfo := nfoSeason{
+		Title:        season.Name,
+		Plot:         season.Overview,
+		Premiered:    string(season.AirDate),
+		SeasonNumber: season.SeasonNumber,
+		UniqueIDs:    uniqueIDs(season.ID, season.ExternalIDs, nil),
+	}
+	if err := writeNFO(filepath.Join(seasonDir, "season.nfo"), nfo); err != nil {
+		return 0, err
+	}
+	count := 1
+
+	for _, episode := range season.Episodes {
+		// 单独保存的单集详情比季详情中的集列表更完整
+		var detailed models.Episode
+		episodePath := path.Join(seasonPath, "episode", strconv.Itoa(episode.EpisodeNumber), "details.json")
+		if ok, err := e.loadModel(key, episodePath, &detailed); err != nil {
+			return count, err
+		} else if ok {
+			episode = detailed
+		}
+
+		nfo := nfoEpisode{
+			Title:     episode.Name,
+			ShowTitle: showTitle,
+			Season:    episode.SeasonNumber,
+			Episode:   episode.EpisodeNumber,
+			Plot:      episode.Overview,
+			Aired:     string(episode.AirDate),
+			Ratings:   tmdbRating(episode.VoteAverage, episode.VoteCount),
+			UniqueIDs: uniqueIDs(episode.ID, episode.ExternalIDs, nil),
+		}
+		if episode.Runtime != nil {
+			nfo.Runtime = *episode.Runtime
+		}
+		// 单集详情附加的演职员中有常驻演员，客串演员排在后面
+		credits := &models.Credits{Cast: episode.GuestStars, Crew: episode.Crew}
+		if episode.Credits != nil {
+			credits.Cast = append(append([]models.Cast(nil), episode.Credits.Cast...), episode.GuestStars...)
+		}
+		nfo.Directors, nfo.Credits, nfo.Actors = creditsInfo(credits)
+
+		name := fmt.Sprintf("S%02dE%02d.nfo", episode.SeasonNumber, episode.EpisodeNumber)
+		if err := writeNFO(filepath.Join(seasonDir, name), nfo); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// missingDetails 条目没有 details.json 时的错误
+func missingDetails(key mediaKey, err error) error {
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("%s 没有 details.json", key)
+}
+
+// tmdbRating 返回TMDB评分，没有评分时返回 nil
+func tmdbRating(average float64, votes int) *nfoRatings {
+	if votes == 0 {
+		return nil
+	}
+	return &nfoRatings{Ratings: []nfoRating{{Name: "themoviedb", Max: 10, Default: true, Value: average, Votes: votes}}}
+}
+
+// uniqueIDs 返回TMDB ID及 external_ids 中的其他站点ID，imdbID 为详情中的 imdb_id，external_ids 中没有时使用
+func uniqueIDs(tmdbID int, external *models.ExternalIDs, imdbID *string) []nfoUniqueID {
+	ids := []nfoUniqueID{{Type: "tmdb", Default: true, Value: strconv.Itoa(tmdbID)}}
+	if external != nil {
+		if external.IMDbID != nil {
+			imdbID = external.IMDbID
+		}
+		if external.TVDBID != nil {
+			ids = append(ids, nfoUniqueID{Type: "tvdb", Value: strconv.Itoa(*external.TVDBID)})
+		}
+		if external.WikidataID != nil && *external.WikidataID != "" {
+			ids = append(ids, nfoUniqueID{Type: "wikidata", Value: *external.WikidataID})
+		}
+	}
+	if imdbID != nil && *imdbID != "" {
+		ids = append(ids, nfoUniqueID{Type: "imdb", Value: *imdbID})
+	}
+	return ids
+}
+
+// creditsInfo 从演职员中整理导演、编剧和演员，演员保持TMDB中的顺序
+func creditsInfo(credits *models.Credits) (directors, writers []string, actors []nfoActor) {
+	if credits == nil {
+		return nil, nil, nil
+	}
+	for _, crew := range credits.Crew {
+		switch {
+		case crew.Job == "Director":
+			directors = appendUnique(directors, crew.Name)
+		case crew.Department == "Writing":
+			writers = appendUnique(writers, crew.Name)
+		}
+	}
+	for i, c := range credits.Cast {
+		actors = append(actors, nfoActor{Name: c.Name, Role: c.Character, Order: i, Thumb: imageURL(c.ProfilePath)})
+	}
+	return directors, writers, actors
+}
+
+// aggregateActors 整理电视剧全部季的演员，一个演员有多个角色时用 " / " 连接
+func aggregateActors(cast []models.AggregateCast) []nfoActor {
+	var actors []nfoActor
+	for i, c := range cast {
+		var roles []string
+		for _, role := range c.Roles {
+			roles = appendUnique(roles, role.Character)
+		}
+		actors = append(actors, nfoActor{Name: c.Name, Role: strings.Join(roles, " / "), Order: i, Thumb: imageURL(c.ProfilePath)})
+	}
+	return actors
+}
+
+// movieCertification 返回电影在指定地区的分级，优先使用院线发行 (type 3) 的分级
+func movieCertification(releaseDates models.ReleaseDates, country string) string {
+	for _, c := range []string{country, fallbackCountry} {
+		for _, result := range releaseDates.Results {
+			if result.ISO3166_1 != c {
+				continue
+			}
+			var certification string
+			for _, release := range result.ReleaseDates {
+				if release.Certification == "" {
+					continue
+				}
+				if release.Type == 3 {
+					return release.Certification
+				}
+				if certification == "" {
+					certification = release.Certification
+				}
+			}
+			if certification != "" {
+				return certification
+			}
+		}
+	}
+	return ""
+}
+
+// tvCertification 返回电视剧在指定地区的内容分级
+func tvCertification(ratings models.ContentRatings, country string) string {
+	for _, c := range []string{country, fallbackCountry} {
+		for _, result := range ratings.Results {
+			if result.ISO3166_1 == c && result.Rating != "" {
+				return result.Rating
+			}
+		}
+	}
+	return ""
+}
+
+// imageURL 返回TMDB图片的完整地址
+func imageURL(p *string) string {
+	if p == nil || *p == "" {
+		return ""
+	}
+	return tmdbImageBaseURL + *p
+}
+
+// appendUnique 追加不重复的非空字符串
+func appendUnique(list []string, s string) []string {
+	if s == "" {
+		return list
+	}
+	for _, existing := range list {
+		if existing == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
+
+// writeNFO 写入 NFO 文件
+func writeNFO(filePath string, v interface{}) error {
+	data, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("生成 %s 失败: %v", filePath, err)
+	}
+	if err := writeFileAtomic(filePath, append([]byte(nfoHeader), append(data, '\n')...)); err != nil {
+		return fmt.Errorf("保存 %s 失败: %v", filePath, err)
+	}
+	return nil
+}
+
+// exportNFO 导出条目的 NFO 文件，keys 为空时导出 tmdb_config 中的全部条目
+func exportNFO(outputDir, destDir, country string, keys []mediaKey) (int, []string, error) {
+	if len(keys) == 0 {
+		var err error
+		if keys, err = listMediaKeys(outputDir); err != nil {
+			return 0, nil, err
+		}
+	}
+	e := &nfoExporter{local: &TMDBFetcher{outputDir: outputDir}, destDir: destDir, country: country}
+	total := 0
+	var failures []string
+	for _, key := range keys {
+		n, err := e.exportMedia(key)
+		total += n
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", key, err))
+			continue
+		}
+		fmt.Printf("已导出: %s (%d 个文件)\n", key, n)
+	}
+	return total, failures, nil
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"tmdb-manager/models"
+)
+
+var updateGolden = flag.Bool("update", false, "用测试的输出更新 testdata 中的期望文件")
+
+// testExportTree 导出测试使用的 tmdb_config: 一部电影和一部有单季、单集数据的电视剧
+var testExportTree = map[string]string{
+	"movie/842675/details.json": `{
+  "id": 842675,
+  "title": "流浪地球2",
+  "original_title": "流浪地球2",
+  "tagline": "危难当前，唯有责任",
+  "overview": "太阳即将毁灭，人类在地球表面建造出巨大的推进器。",
+  "runtime": 173,
+  "release_date": "2023-01-22",
+  "vote_average": 7.2,
+  "vote_count": 811,
+  "imdb_id": "tt13539646",
+  "genres": [{"id": 878, "name": "科幻"}, {"id": 28, "name": "动作"}],
+  "production_countries": [{"iso_3166_1": "CN", "name": "中国"}],
+  "production_companies": [{"id": 1, "name": "中国电影股份有限公司", "origin_country": "CN"}],
+  "belongs_to_collection": {"id": 1009125, "name": "流浪地球（系列）"},
+  "credits": {
+    "cast": [
+      {"id": 1, "name": "吴京", "character": "刘培强", "profile_path": "/wujing.jpg", "order": 0},
+      {"id": 2, "name": "刘德华", "character": "图恒宇", "profile_path": null, "order": 1}
+    ],
+    "crew": [
+      {"id": 3, "name": "郭帆", "job": "Director", "department": "Directing"},
+      {"id": 3, "name": "郭帆", "job": "Screenplay", "department": "Writing"},
+      {"id": 4, "name": "叶濡畅", "job": "Screenplay", "department": "Writing"}
+    ]
+  },
+  "external_ids": {"imdb_id": "tt13539646", "wikidata_id": "Q106498580"},
+  "translations": {"translations": []}
+}`,
+	// 没有中国大陆的分级，使用美国院线发行 (type 3) 的分级
+	"movie/842675/release_dates.json": `{
+  "id": 842675,
+  "results": [
+    {"iso_3166_1": "AU", "release_dates": [{"certification": "M", "type": 4, "release_date": "2023-01-22T00:00:00.000Z"}]},
+    {"iso_3166_1": "US", "release_dates": [
+      {"certification": "", "type": 2, "release_date": "2023-01-20T00:00:00.000Z"},
+      {"certification": "NR", "type": 4, "release_date": "2023-02-01T00:00:00.000Z"},
+      {"certification": "PG-13", "type": 3, "release_date": "2023-01-22T00:00:00.000Z"}
+    ]}
+  ]
+}`,
+	"tv/95480/details.json": `{
+  "id": 95480,
+  "name": "三体",
+  "original_name": "三体",
+  "overview": "纳米科学家汪淼被卷入一桩离奇的科学家自杀案。",
+  "first_air_date": "2023-01-15",
+  "status": "Ended",
+  "vote_average": 7.6,
+  "vote_count": 120,
+  "genres": [{"id": 10765, "name": "Sci-Fi & Fantasy"}],
+  "networks": [{"id": 1, "name": "CCTV-8"}],
+  "created_by": [{"id": 5, "name": "刘慈欣"}],
+  "seasons": [{"id": 1, "season_number": 1, "name": "第 1 季", "episode_count": 1}],
+  "aggregate_credits": {
+    "cast": [
+      {"id": 6, "name": "张鲁一", "profile_path": null, "roles": [{"character": "汪淼", "episode_count": 30}, {"character": "汪淼（少年）", "episode_count": 1}]}
+    ],
+    "crew": []
+  },
+  "external_ids": {"imdb_id": "tt20242042", "tvdb_id": 420531}
+}`,
+	"tv/95480/content_ratings.json": `{
+  "id": 95480,
+  "results": [{"iso_3166_1": "CN", "rating": "13+"}, {"iso_3166_1": "US", "rating": "TV-14"}]
+}`,
+	"tv/95480/season/1/details.json": `{
+  "id": 1,
+  "season_number": 1,
+  "name": "第 1 季",
+  "overview": "第一季",
+  "air_date": "2023-01-15",
+  "episodes": [
+    {"id": 10, "season_number": 1, "episode_number": 1, "name": "第 1 集", "overview": "季详情中的简介", "air_date": "2023-01-15", "runtime": 45, "vote_average": 0, "vote_count": 0}
+  ]
+}`,
+	// 单集详情比季详情中的集列表更完整
+	"tv/95480/season/1/episode/1/details.json": `{
+  "id": 10,
+  "season_number": 1,
+  "episode_number": 1,
+  "name": "第 1 集",
+  "overview": "汪淼参加了一次神秘的会议。",
+  "air_date": "2023-01-15",
+  "runtime": 45,
+  "vote_average": 8,
+  "vote_count": 5,
+  "crew": [{"id": 7, "name": "杨磊", "job": "Director", "department": "Directing"}],
+  "guest_stars": [{"id": 8, "name": "客串演员", "character": "路人", "profile_path": null}],
+  "credits": {"cast": [{"id": 6, "name": "张鲁一", "character": "汪淼", "profile_path": "/zhang.jpg"}], "crew": []}
+}`,
+}
+
+// checkGolden 比较导出的文件与 testdata 中的期望文件，-update 时更新期望文件
+func checkGolden(t *testing.T, gotPath, goldenPath string) {
+	t.Helper()
+	got, err := os.ReadFile(gotPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *updateGolden {
+		if err := os.MkdirAll(filepath.Dir(goldenPath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(goldenPath, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("%s 与 %s 不一致:\n%s", gotPath, goldenPath, got)
+	}
+}
+
+func TestExportNFO(t *testing.T) {
+	root := t.TempDir()
+	configDir := filepath.Join(root, "tmdb_config")
+	writeFiles(t, configDir, testExportTree)
+	destDir := filepath.Join(root, "nfo")
+
+	total, failures, err := exportNFO(configDir, destDir, "CN", nil)
+	if err != nil || len(failures) > 0 {
+		t.Fatalf("exportNFO() 失败: %v %v", err, failures)
+	}
+	if total != 4 {
+		t.Errorf("导出了 %d 个文件, 期望 4 个", total)
+	}
+	for _, relPath := range []string{
+		"movie/842675/movie.nfo",
+		"tv/95480/tvshow.nfo",
+		"tv/95480/Season 1/season.nfo",
+		"tv/95480/Season 1/S01E01.nfo",
+	} {
+		checkGolden(t, filepath.Join(destDir, filepath.FromSlash(relPath)), filepath.Join("testdata", "nfo", filepath.FromSlash(relPath)))
+	}
+}
+
+func TestCertificationFallback(t *testing.T) {
+	release := func(country, certification string, releaseType int) models.CountryReleaseDates {
+		return models.CountryReleaseDates{ISO3166_1: country, ReleaseDates: []models.ReleaseDate{{Certification: certification, Type: releaseType}}}
+	}
+	tests := []struct {
+		name    string
+		results []models.CountryReleaseDates
+		want    string
+	}{
+		{name: "使用指定地区的分级", results: []models.CountryReleaseDates{release("US", "R", 3), release("CN", "PG", 4)}, want: "PG"},
+		{name: "指定地区没有分级时使用美国的分级", results: []models.CountryReleaseDates{release("CN", "", 3), release("US", "R", 3)}, want: "R"},
+		{name: "都没有分级", results: []models.CountryReleaseDates{release("GB", "15", 3)}, want: ""},
+	}
+	for _, tt := range tests {
+		if got := movieCertification(models.ReleaseDates{Results: tt.results}, "CN"); got != tt.want {
+			t.Errorf("%s: movieCertification() = %q, 期望 %q", tt.name, got, tt.want)
+		}
+	}
+
+	ratings := models.ContentRatings{Results: []models.ContentRating{{ISO3166_1: "CN", Rating: ""}, {ISO3166_1: "US", Rating: "TV-MA"}}}
+	if got := tvCertification(ratings, "CN"); got != "TV-MA" {
+		t.Errorf("tvCertification() = %q, 期望使用美国的分级 TV-MA", got)
+	}
+	if got := tvCertification(ratings, "US"); got != "TV-MA" {
+		t.Errorf("tvCertification() = %q, 期望 TV-MA", got)
+	}
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// overlayFileName 覆盖文件名
+// 使用覆盖文件维护的条目目录中只有这一个文件，内容为各数据文件的 JSON Merge Patch (RFC 7386):
+//
+//	{
+//	  "details.json": {"title": "流浪地球2", "tagline": null},
+//	  "season/1/details.json": {"name": "第一季"}
+//	}
+//
+// 字段值为 null 表示删除该字段；数据文件的值为 null 表示整个文件不发布
+// 因此覆盖文件无法把字段设为 null，本地为 null 而TMDB有值 (或没有该字段) 的数据文件不能转换
+const overlayFileName = "overlay.json"
+
+// defaultExpandDir 展开覆盖文件时的默认输出目录，相对于 scripts/cli 目录
+const defaultExpandDir = "../tmdb_expanded"
+
+// overlay 一个条目的覆盖内容，key 与 mediaFiles 相同
+type overlay map[string]interface{}
+
+// overlayPath 返回条目的覆盖文件路径
+func (f *TMDBFetcher) overlayPath(mediaType, mediaID string) string {
+	return filepath.Join(f.mediaDir(mediaType, mediaID), overlayFileName)
+}
+
+// hasOverlay 判断条目是否使用覆盖文件维护
+func (f *TMDBFetcher) hasOverlay(mediaType, mediaID string) bool {
+	_, err := os.Stat(f.overlayPath(mediaType, mediaID))
+	return err == nil
+}
+
+// loadOverlay 读取条目的覆盖文件
+func (f *TMDBFetcher) loadOverlay(mediaType, mediaID string) (overlay, error) {
+	data, err := loadJSON(f.overlayPath(mediaType, mediaID))
+	if err != nil {
+		return nil, err
+	}
+	for relPath, patch := range data {
+		if _, ok := patch.(map[string]interface{}); !ok && patch != nil {
+			return nil, fmt.Errorf("覆盖文件中 %s 的内容必须是对象或 null", relPath)
+		}
+	}
+	return overlay(data), nil
+}
+
+// mergePatch 按 RFC 7386 将 patch 应用到 target，返回新的值，不修改 target
+func mergePatch(target, patch interface{}) interface{} {
+	patchMap, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	result := make(map[string]interface{})
+	if targetMap, ok := target.(map[string]interface{}); ok {
+		for k, v := range targetMap {
+			result[k] = v
+		}
+	}
+	for k, v := range patchMap {
+		if v == nil {
+			delete(result, k)
+			continue
+		}
+		result[k] = mergePatch(result[k], v)
+	}
+	return result
+}
+
+// createMergePatch 生成把 source 变为 target 的最小 Merge Patch，两者相同时返回 false
+// Merge Patch 无法表示数组中的单个元素，数组有任何变化时整个数组都会写入补丁
+func createMergePatch(source, target interface{}) (interface{}, bool) {
+	if reflect.DeepEqual(source, target) {
+		return nil, false
+	}
+
+	sourceMap, sourceIsMap := source.(map[string]interface{})
+	targetMap, targetIsMap := target.(map[string]interface{})
+	if !sourceIsMap || !targetIsMap {
+		return target, true
+	}
+
+	patch := make(map[string]interface{})
+	for k, sv := range sourceMap {
+		tv, ok := targetMap[k]
+		if !ok {
+			patch[k] = nil
+			continue
+		}
+		if p, changed := createMergePatch(sv, tv); changed {
+			patch[k] = p
+		}
+	}
+	for k, tv := range targetMap {
+		if _, ok := sourceMap[k]; !ok {
+			patch[k] = tv
+		}
+	}
+	return patch, true
+}
+
+// applyOverlay 将覆盖内容应用到TMDB数据上，返回新的文件集合
+func applyOverlay(files mediaFiles, o overlay) mediaFiles {
+	result := make(mediaFiles, len(files))
+	for relPath, data := range files {
+		result[relPath] = data
+	}
+	for relPath, patch := range o {
+		if patch == nil {
+			delete(result, relPath)
+			continue
+		}
+		// 覆盖文件中可以包含TMDB没有的文件，此时补丁本身就是文件内容
+		var target interface{}
+		if data, ok := result[relPath]; ok {
+			target = data
+		}
+		merged, _ := mergePatch(target, patch).(map[string]interface{})
+		result[relPath] = merged
+	}
+	return result
+}
+
+// loadMediaFiles 读取条目目录或快照目录下的全部数据文件，不包括覆盖文件和快照元信息
+func loadMediaFiles(dir string) (mediaFiles, error) {
+	files := make(mediaFiles)
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(p) != ".json" || d.Name() == overlayFileName || d.Name() == snapshotMetaFile {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		data, err := loadJSON(p)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = data
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("读取 %s 失败: %v", dir, err)
+	}
+	return files, nil
+}
+
+// expandOverlay 获取最新的TMDB数据并应用覆盖文件，将完整的数据文件写入 destRoot/{movie|tv}/{id}
+func (f *TMDBFetcher) expandOverlay(mediaType, mediaID, destRoot string) (err error) {
+	o, err := f.loadOverlay(mediaType, mediaID)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("%s/%s 没有覆盖文件 (%s)", mediaType, mediaID, f.overlayPath(mediaType, mediaID))
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\n开始展开%s ID: %s 的覆盖文件...\n", mediaLabel(mediaType), mediaID)
+	remoteFiles, err := f.fetchMediaFiles(mediaType, mediaID)
+	if err != nil {
+		return err
+	}
+	files := applyOverlay(remoteFiles, o)
+
+	stagingDir, err := newStagingDir(destRoot, mediaType, mediaID)
+	if err != nil {
+		return err
+	}
+	defer discardStagingDir(stagingDir, &err)
+
+	if err := saveMediaFiles(files, stagingDir); err != nil {
+		return err
+	}
+	targetDir := filepath.Join(destRoot, mediaType, mediaID)
+	if err := replaceWithStagingDir(stagingDir, targetDir); err != nil {
+		return err
+	}
+
+	fmt.Printf("\n✓ 已应用 %d 个文件的覆盖内容\n", len(o))
+	fmt.Printf("  目录: %s\n", targetDir)
+	return nil
+}
+
+// convertToOverlay 将完整的数据文件转换为覆盖文件
+// 与最新的TMDB数据比较，只保留本地修改过的字段，转换后条目目录中只剩 overlay.json
+func (f *TMDBFetcher) convertToOverlay(mediaType, mediaID string) (err error) {
+	label := mediaLabel(mediaType)
+	baseDir := f.mediaDir(mediaType, mediaID)
+	if !checkDirectoryExists(baseDir) {
+		return fmt.Errorf("目录不存在: %s，请先获取该%s的数据", baseDir, label)
+	}
+	if f.hasOverlay(mediaType, mediaID) {
+		return fmt.Errorf("%s/%s 已经使用覆盖文件维护", mediaType, mediaID)
+	}
+
+	fmt.Printf("\n开始转换%s ID: %s 的数据...\n", label, mediaID)
+	localFiles, err := loadMediaFiles(baseDir)
+	if err != nil {
+		return err
+	}
+	remoteFiles, err := f.fetchMediaFiles(mediaType, mediaID)
+	if err != nil {
+		return err
+	}
+
+	// 本地没有的文件（如本地数据获取之后TMDB新增的一季）直接使用TMDB数据，不写入覆盖文件
+	o := make(overlay)
+	for relPath, local := range localFiles {
+		if patch, changed := createMergePatch(mapOrNil(remoteFiles, relPath), local); changed {
+			o[relPath] = patch
+		}
+	}
+
+	// 确认展开后与本地数据完全一致，Merge Patch 中的 null 会被当作删除字段，展开后会丢失本地的 null 值
+	expanded := applyOverlay(remoteFiles, o)
+	var lossy []string
+	for relPath, local := range localFiles {
+		if !reflect.DeepEqual(expanded[relPath], local) {
+			lossy = append(lossy, relPath)
+		}
+	}
+	if len(lossy) > 0 {
+		sort.Strings(lossy)
+		return fmt.Errorf("以下文件中有字段在本地为 null 而TMDB有值或没有该字段，覆盖文件中的 null 表示删除字段，无法表示这些值，未转换:\n  %s\n请删除这些字段或改为其他值后再转换", strings.Join(lossy, "\n  "))
+	}
+
+	oldSize, err := dirSize(baseDir)
+	if err != nil {
+		return err
+	}
+
+	stagingDir, err := newStagingDir(f.outputDir, mediaType, mediaID)
+	if err != nil {
+		return err
+	}
+	defer discardStagingDir(stagingDir, &err)
+
+	overlayFile := filepath.Join(stagingDir, overlayFileName)
+	if err := saveJSON(o, overlayFile); err != nil {
+		return err
+	}
+	info, err := os.Stat(overlayFile)
+	if err != nil {
+		return err
+	}
+
+	// 先保存本次比较使用的TMDB数据，pack、diff 和 refresh 都需要快照才能展开覆盖文件
+	// 快照保存失败时不替换条目目录，完整的数据文件保持原样
+	if err := f.saveSnapshot(mediaType, mediaID, remoteFiles); err != nil {
+		return fmt.Errorf("保存TMDB原始数据快照失败，未转换: %v", err)
+	}
+	if err := replaceWithStagingDir(stagingDir, baseDir); err != nil {
+		return err
+	}
+
+	paths := make([]string, 0, len(o))
+	for relPath := range o {
+		paths = append(paths, relPath)
+	}
+	sort.Strings(paths)
+
+	fmt.Printf("\n✓ %s %s 已转换为覆盖文件\n", label, mediaID)
+	for _, relPath := range paths {
+		data, _ := json.Marshal(o[relPath])
+		fmt.Printf("  %s (%d 字节)\n", relPath, len(data))
+	}
+	fmt.Printf("  大小: %d 字节 → %d 字节\n", oldSize, info.Size())
+	return nil
+}
+
+// mapOrNil 取出文件内容，文件不存在时返回 nil 而不是类型化的空 map
+func mapOrNil(files mediaFiles, relPath string) interface{} {
+	if data, ok := files[relPath]; ok {
+		return data
+	}
+	return nil
+}
+
+// dirSize 统计目录下全部文件的大小
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	return size, err
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"tmdb-manager/updater"
+)
+
+// 发布包相关的默认值，目录相对于 scripts/cli 目录
+const (
+	defaultPackDir   = ".."
+	packArchiveName  = "tmdb_config.tar.gz"
+	packChecksumName = packArchiveName + ".sha256"
+)
+
+// packModTime 压缩包中所有条目使用的修改时间，内容相同时生成的压缩包完全一致
+var packModTime = time.Unix(0, 0).UTC()
+
+// packFile 发布包中的一个文件
+type packFile struct {
+	relPath string // 相对于 tmdb_config 的路径，使用 / 分隔
+	data    []byte
+}
+
+// collectPackFiles 收集 tmdb_config 中需要发布的全部文件，按路径排序
+// 隐藏文件和目录 (如 .staging) 不发布；使用覆盖文件的条目在 tmdb_upstream 的快照上应用覆盖内容，发布完整的数据文件
+func (f *TMDBFetcher) collectPackFiles() ([]packFile, error) {
+	if !checkDirectoryExists(f.outputDir) {
+		return nil, fmt.Errorf("目录不存在: %s", f.outputDir)
+	}
+
+	var files []packFile
+	err := filepath.WalkDir(f.outputDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("读取 %s 失败: %v", p, err)
+		}
+		rel, err := filepath.Rel(f.outputDir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if d.IsDir() {
+			parts := strings.Split(rel, "/")
+			if len(parts) == 2 && (parts[0] == "movie" || parts[0] == "tv") && f.hasOverlay(parts[0], parts[1]) {
+				expanded, err := f.expandOverlayOffline(parts[0], parts[1])
+				if err != nil {
+					return err
+				}
+				files = append(files, expanded...)
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return fmt.Errorf("读取 %s 失败: %v", p, err)
+		}
+		files = append(files, packFile{relPath: rel, data: data})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].relPath < files[j].relPath })
+	return files, nil
+}
+
+// expandOverlayOffline 在 tmdb_upstream 的快照上应用覆盖文件，不请求TMDB
+func (f *TMDBFetcher) expandOverlayOffline(mediaType, mediaID string) ([]packFile, error) {
+	snapshotDir := f.snapshotDir(mediaType, mediaID)
+	if !checkDirectoryExists(snapshotDir) {
+		return nil, fmt.Errorf("%s/%s 使用覆盖文件维护，但 %s 中没有TMDB原始数据快照，无法生成完整的数据文件", mediaType, mediaID, upstreamDirName)
+	}
+	snapshot, err := loadMediaFiles(snapshotDir)
+	if err != nil {
+		return nil, err
+	}
+	o, err := f.loadOverlay(mediaType, mediaID)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []packFile
+	for relPath, data := range applyOverlay(snapshot, o) {
+		encoded, err := encodeJSONFile(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s/%s/%s: %v", mediaType, mediaID, relPath, err)
+		}
+		files = append(files, packFile{relPath: path.Join(mediaType, mediaID, relPath), data: encoded})
+	}
+	return files, nil
+}
+
+// encodeJSONFile 按 saveJSON 的格式编码JSON
+func encodeJSONFile(data interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writePackArchive 写入 tar.gz，目录和文件按路径排序，修改时间、属主和权限固定
+// files 放在 tmdb_config 目录中，rootFiles 直接放在顶层目录中 (如增量包的 delta.json)
+func writePackArchive(w io.Writer, files []packFile, rootFiles ...packFile) error {
+	gz, err := gzip.NewWriterLevel(w, gzip.BestCompression)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(gz)
+
+	prefix := path.Join(updater.RootDir, updater.ConfigDirName)
+	dirs := map[string]bool{updater.RootDir: true, prefix: true}
+	for _, file := range files {
+		for dir := path.Dir(path.Join(prefix, file.relPath)); dir != prefix; dir = path.Dir(dir) {
+			dirs[dir] = true
+		}
+	}
+
+	type entry struct {
+		name string
+		file *packFile
+	}
+	entries := make([]entry, 0, len(dirs)+len(files))
+	for dir := range dirs {
+		entries = append(entries, entry{name: dir + "/"})
+	}
+	for i := range files {
+		entries = append(entries, entry{name: path.Join(prefix, files[i].relPath), file: &files[i]})
+	}
+	for i := range rootFiles {
+		entries = append(entries, entry{name: path.Join(updater.RootDir, rootFiles[i].relPath), file: &rootFiles[i]})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].name < entries[j].name })
+
+	for _, e := range entries {
+		header := &tar.Header{
+			Name:     e.name,
+			ModTime:  packModTime,
+			Typeflag: tar.TypeDir,
+			Mode:     0755,
+		}
+		if e.file != nil {
+			header.Typeflag = tar.TypeReg
+			header.Mode = 0644
+			header.Size = int64(len(e.file.data))
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if e.file != nil {
+			if _, err := tw.Write(e.file.data); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// packRelease 生成发布包: tmdb_config.tar.gz、sha256sum 格式的校验文件和 manifest.json
+// version 为发布版本号；repoDir 为项目根目录，用于查找每个条目最后修改的提交，为空时不查找
+// basePath 为上一个版本的 manifest.json，不为空时同时生成相对该版本的增量包
+func (f *TMDBFetcher) packRelease(destDir, version, repoDir, basePath string) (*updater.Manifest, error) {
+	var base *updater.Manifest
+	if basePath != "" {
+		var err error
+		if base, err = updater.LoadManifest(basePath); err != nil {
+			return nil, fmt.Errorf("读取上一个版本的 %s 失败: %v", updater.ManifestName, err)
+		}
+	}
+	files, err := f.collectPackFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	var archive bytes.Buffer
+	if err := writePackArchive(&archive, files); err != nil {
+		return nil, fmt.Errorf("生成压缩包失败: %v", err)
+	}
+	manifest, err := f.buildManifest(version, repoDir, files)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(archive.Bytes())
+	manifest.Archive = packArchiveName
+	manifest.Size = int64(archive.Len())
+	manifest.SHA256 = hex.EncodeToString(sum[:])
+
+	var delta []byte
+	if base != nil {
+		if delta, manifest.Delta, err = encodeDeltaArchive(base, version, files); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return nil, fmt.Errorf("创建目录失败: %v", err)
+	}
+	// 旧的签名和增量包与新生成的文件不再对应
+	for _, name := range []string{packArchiveName + updater.SignatureExt, updater.ManifestName + updater.SignatureExt, deltaArchiveName, deltaArchiveName + updater.SignatureExt} {
+		if err := os.Remove(filepath.Join(destDir, name)); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("删除旧的文件失败: %v", err)
+		}
+	}
+	if err := writeFileAtomic(filepath.Join(destDir, packArchiveName), archive.Bytes()); err != nil {
+		return nil, fmt.Errorf("保存压缩包失败: %v", err)
+	}
+	checksum := fmt.Sprintf("%s  %s\n", manifest.SHA256, packArchiveName)
+	if err := writeFileAtomic(filepath.Join(destDir, packChecksumName), []byte(checksum)); err != nil {
+		return nil, fmt.Errorf("保存校验文件失败: %v", err)
+	}
+	if manifest.Delta != nil {
+		if err := writeFileAtomic(filepath.Join(destDir, deltaArchiveName), delta); err != nil {
+			return nil, fmt.Errorf("保存增量包失败: %v", err)
+		}
+	}
+	if err := saveJSON(manifest, filepath.Join(destDir, updater.ManifestName)); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"tmdb-manager/updater"
+)
+
+func TestPackReleaseIsReproducible(t *testing.T) {
+	root := t.TempDir()
+	configDir := filepath.Join(root, "tmdb_config")
+	writeFiles(t, configDir, map[string]string{
+		"tv/2/season/1/details.json": `{"season_number": 1}`,
+		"tv/2/details.json":          `{"id": 2, "name": "B"}`,
+		"movie/10/details.json":      `{"id": 10, "title": "J"}`,
+		"movie/1/details.json":       `{"id": 1, "title": "A"}`,
+		"movie/1/release_dates.json": `{"results": []}`,
+		".staging/x/tmp.json":        `{}`,
+	})
+	f := &TMDBFetcher{outputDir: configDir}
+
+	if _, err := f.packRelease(filepath.Join(root, "first"), "v1", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	// 文件的修改时间不同时也应生成完全相同的发布包
+	later := time.Now().Add(time.Hour)
+	filepath.WalkDir(configDir, func(p string, d os.DirEntry, err error) error {
+		if err == nil {
+			os.Chtimes(p, later, later)
+		}
+		return nil
+	})
+	if _, err := f.packRelease(filepath.Join(root, "second"), "v1", "", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{packArchiveName, packChecksumName, updater.ManifestName} {
+		first, err := os.ReadFile(filepath.Join(root, "first", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := os.ReadFile(filepath.Join(root, "second", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(first, second) {
+			t.Errorf("两次生成的 %s 不一致", name)
+		}
+	}
+}
+
+func TestPackArchiveEntries(t *testing.T) {
+	root := t.TempDir()
+	configDir := filepath.Join(root, "tmdb_config")
+	writeFiles(t, configDir, map[string]string{
+		"tv/2/details.json":          `{"id": 2, "name": "B"}`,
+		"movie/1/details.json":       `{"id": 1, "title": "A"}`,
+		"movie/1/release_dates.json": `{"results": []}`,
+		".staging/x/tmp.json":        `{}`,
+	})
+	f := &TMDBFetcher{outputDir: configDir}
+	if _, err := f.packRelease(root, "v1", "", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := os.ReadFile(filepath.Join(root, packArchiveName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	var names []string
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, header.Name)
+		if !header.ModTime.Equal(packModTime) {
+			t.Errorf("%s 的修改时间 = %v, 期望 %v", header.Name, header.ModTime, packModTime)
+		}
+		if header.Uid != 0 || header.Gid != 0 || header.Uname != "" || header.Gname != "" {
+			t.Errorf("%s 的属主 = %d:%d (%s:%s), 期望 0:0", header.Name, header.Uid, header.Gid, header.Uname, header.Gname)
+		}
+		wantMode := int64(0644)
+		if header.Typeflag == tar.TypeDir {
+			wantMode = 0755
+		}
+		if header.Mode != wantMode {
+			t.Errorf("%s 的权限 = %o, 期望 %o", header.Name, header.Mode, wantMode)
+		}
+	}
+
+	if !sort.StringsAreSorted(names) {
+		t.Errorf("压缩包中的条目没有按路径排序: %v", names)
+	}
+	prefix := updater.RootDir + "/" + updater.ConfigDirName + "/"
+	want := []string{
+		updater.RootDir + "/",
+		prefix,
+		prefix + "movie/",
+		prefix + "movie/1/",
+		prefix + "movie/1/details.json",
+		prefix + "movie/1/release_dates.json",
+		prefix + "tv/",
+		prefix + "tv/2/",
+		prefix + "tv/2/details.json",
+	}
+	if strings.Join(names, "\n") != strings.Join(want, "\n") {
+		t.Errorf("压缩包中的条目 = %v, 期望 %v", names, want)
+	}
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// defaultCacheDir 代理缓存TMDB响应的目录，相对于 scripts/cli 目录
+const defaultCacheDir = "../tmdb_cache"
+
+// defaultCacheTTL 缓存的有效期，过期后重新请求TMDB，请求失败时仍使用过期的缓存
+const defaultCacheTTL = 24 * time.Hour
+
+// proxyForwardHeaders 转发给TMDB的请求头
+var proxyForwardHeaders = []string{"Authorization", "Accept"}
+
+// proxyServer 转发请求到TMDB，并把 tmdb_config 中维护的修改合并到响应中
+type proxyServer struct {
+	fetcher  *TMDBFetcher
+	cacheDir string        // 为空时不缓存
+	cacheTTL time.Duration // 缓存有效期
+}
+
+// upstreamResponse TMDB的响应
+type upstreamResponse struct {
+	status      int
+	contentType string
+	body        []byte
+	cache       string // 缓存状态: HIT、MISS、STALE，不缓存时为空
+}
+
+func (s *proxyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status, note := s.serve(w, r)
+	fmt.Printf("%s %s %s %d %s%v\n", start.Format("15:04:05"), r.Method, r.URL.Path, status, note, time.Since(start).Round(time.Millisecond))
+}
+
+// serve 处理一个请求，返回HTTP状态码和日志中的附加说明
+func (s *proxyServer) serve(w http.ResponseWriter, r *http.Request) (int, string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return http.StatusMethodNotAllowed, ""
+	}
+	if !strings.HasPrefix(r.URL.Path, "/3/") {
+		return writeTMDBStatus(w, statusNotFound), ""
+	}
+	r = withDefaultLanguage(r, s.fetcher.config.Language)
+
+	resp, err := s.fetch(r)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "错误: %s: %v\n", r.URL.Path, err)
+		return writeTMDBStatus(w, statusInternal), ""
+	}
+	note := ""
+	if resp.cache != "" {
+		w.Header().Set("X-Cache", resp.cache)
+		note = resp.cache + " "
+	}
+
+	// 只合并与维护数据语言一致的成功响应
+	req, ok := parseServePath(strings.TrimPrefix(r.URL.Path, "/3/"))
+	if ok && resp.status == http.StatusOK && strings.EqualFold(r.URL.Query().Get("language"), s.fetcher.config.Language) {
+		merged, changed, err := s.fetcher.mergeCurated(resp.body, req, r.URL.Query().Get("append_to_response"))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "错误: %s: 合并维护数据失败: %v\n", r.URL.Path, err)
+		} else if changed {
+			return writeJSONResponse(w, resp.status, merged), note + "已合并维护数据 "
+		}
+	}
+
+	if resp.contentType != "" {
+		w.Header().Set("Content-Type", resp.contentType)
+	}
+	w.WriteHeader(resp.status)
+	w.Write(resp.body)
+	return resp.status, note
+}
+
+// withDefaultLanguage 没有指定 language 的请求使用配置的语言，返回修改后的副本
+// TMDB对这样的请求默认返回英文，而维护的数据是配置语言的，按配置语言请求才能与维护数据合并
+func withDefaultLanguage(r *http.Request, language string) *http.Request {
+	query := r.URL.Query()
+	if query.Get("language") != "" {
+		return r
+	}
+	query.Set("language", language)
+	r = r.Clone(r.Context())
+	r.URL.RawQuery = query.Encode()
+	return r
+}
+
+// fetch 从缓存或TMDB获取响应，只缓存成功的响应
+func (s *proxyServer) fetch(r *http.Request) (*upstreamResponse, error) {
+	cachePath := s.cachePath(r)
+	var cached []byte
+	if cachePath != "" {
+		if info, err := os.Stat(cachePath); err == nil {
+			if cached, err = os.ReadFile(cachePath); err == nil && time.Since(info.ModTime()) < s.cacheTTL {
+				return &upstreamResponse{status: http.StatusOK, contentType: "application/json;charset=utf-8", body: cached, cache: "HIT"}, nil
+			}
+		}
+	}
+
+	resp, err := s.fetchUpstream(r)
+	if cached != nil && (err != nil || resp.status >= http.StatusInternalServerError) {
+		// TMDB暂时不可用时使用过期的缓存
+		if err == nil {
+			err = fmt.Errorf("TMDB返回 %d", resp.status)
+		}
+		fmt.Fprintf(os.Stderr, "警告: %s: %v，使用过期的缓存\n", r.URL.Path, err)
+		return &upstreamResponse{status: http.StatusOK, contentType: "application/json;charset=utf-8", body: cached, cache: "STALE"}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if cachePath != "" {
+		resp.cache = "MISS"
+		if resp.status == http.StatusOK {
+			if err := writeFileAtomic(cachePath, resp.body); err != nil {
+				fmt.Fprintf(os.Stderr, "警告: 写入缓存失败: %v\n", err)
+			}
+		}
+	}
+	return resp, nil
+}
+
+// fetchUpstream 把请求转发到TMDB，客户端没有提供API Key时使用配置文件中的API Key
+func (s *proxyServer) fetchUpstream(r *http.Request) (*upstreamResponse, error) {
+	query := r.URL.Query()
+	if query.Get("api_key") == "" && r.Header.Get("Authorization") == "" {
+		query.Set("api_key", s.fetcher.config.TMDBAPIKey)
+	}
+	reqURL := s.fetcher.baseURL + strings.TrimPrefix(r.URL.Path, "/3") + "?" + query.Encode()
+
+	upstream, err := http.NewRequestWithContext(r.Context(), http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range proxyForwardHeaders {
+		if v := r.Header.Get(name); v != "" {
+			upstream.Header.Set(name, v)
+		}
+	}
+
+	s.fetcher.limiter.wait()
+	resp, err := s.fetcher.httpClient.Do(upstream)
+	if err != nil {
+		return nil, &requestError{op: "请求失败", err: err}
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &requestError{op: "读取响应失败", err: err}
+	}
+	return &upstreamResponse{status: resp.StatusCode, contentType: resp.Header.Get("Content-Type"), body: body}, nil
+}
+
+// cachePath 返回请求对应的缓存文件路径，不缓存的请求返回空字符串
+// 缓存键不包含 api_key，带有会话的请求 (如账户信息) 不缓存
+func (s *proxyServer) cachePath(r *http.Request) string {
+	if s.cacheDir == "" || r.Header.Get("Authorization") != "" {
+		return ""
+	}
+	query := r.URL.Query()
+	if query.Get("session_id") != "" || query.Get("guest_session_id") != "" ||
+		strings.HasPrefix(r.URL.Path, "/3/account") || strings.HasPrefix(r.URL.Path, "/3/authentication") {
+		return ""
+	}
+	query.Del("api_key")
+	sum := sha256.Sum256([]byte(r.URL.Path + "?" + query.Encode()))
+	key := hex.EncodeToString(sum[:])
+	return filepath.Join(s.cacheDir, key[:2], key+".json")
+}
+
+// curatedTextFields 没有快照时从本地数据合并的字段: 维护者修正的标题、简介、翻译和别名
+var curatedTextFields = []string{"title", "name", "overview", "tagline", "translations", "alternative_titles"}
+
+// curatedPatch 返回维护的数据文件相对TMDB数据的修改，以 Merge Patch 表示，没有维护该文件时返回 nil
+//   - 使用覆盖文件的条目: 覆盖文件中该文件的内容
+//   - 有快照的条目: 快照与本地文件的差异，只包含维护者修改过的字段
+//   - 没有快照的条目: 本地文件中维护的文本字段 (curatedTextFields，去掉 null 值)
+//     无法区分本地修改和获取时的TMDB数据，评分、季列表等其他字段使用TMDB的最新数据
+func (f *TMDBFetcher) curatedPatch(mediaType, mediaID, relPath string) (map[string]interface{}, error) {
+	if !checkDirectoryExists(f.mediaDir(mediaType, mediaID)) {
+		return nil, nil
+	}
+	if f.hasOverlay(mediaType, mediaID) {
+		o, err := f.loadOverlay(mediaType, mediaID)
+		if err != nil {
+			return nil, err
+		}
+		patch, _ := o[relPath].(map[string]interface{})
+		return patch, nil
+	}
+
+	local, err := loadJSON(filepath.Join(f.mediaDir(mediaType, mediaID), filepath.FromSlash(relPath)))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := f.loadSnapshotFile(mediaType, mediaID, relPath)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot == nil {
+		patch := make(map[string]interface{})
+		for _, key := range curatedTextFields {
+			if v, ok := local[key]; ok && v != nil {
+				patch[key] = dropNulls(v)
+			}
+		}
+		return patch, nil
+	}
+	patch, _ := createMergePatch(snapshot, local)
+	result, _ := patch.(map[string]interface{})
+	return result, nil
+}
+
+// dropNulls 递归删除对象中的 null 值，使其作为 Merge Patch 时不会删除字段
+func dropNulls(v interface{}) interface{} {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return v
+	}
+	result := make(map[string]interface{}, len(m))
+	for k, child := range m {
+		if child != nil {
+			result[k] = dropNulls(child)
+		}
+	}
+	return result
+}
+
+// mergeCurated 把维护的修改合并到TMDB响应中，条目没有维护或没有相关修改时返回 false
+func (f *TMDBFetcher) mergeCurated(body []byte, req serveRequest, appendToResponse string) (map[string]interface{}, bool, error) {
+	// 按请求的资源找出对应的数据文件及补丁在文件中的位置
+	type target struct {
+		relPath  string
+		patchKey string // 只使用补丁中的这个字段，为空表示整个补丁
+		respKey  string // 合并到响应中的这个字段，为空表示整个响应
+	}
+	var targets []target
+	details := path.Join(req.relDir, "details.json")
+	switch {
+	case req.resource == "":
+		targets = append(targets, target{relPath: details})
+		for _, key := range strings.Split(appendToResponse, ",") {
+			key = strings.TrimSpace(key)
+			switch {
+			case serveAppendKeys[key]:
+			case req.relDir == "" && key == serveFileResources[req.mediaType]:
+				targets = append(targets, target{relPath: key + ".json", respKey: key})
+			case req.mediaType == "tv" && req.relDir == "" && strings.HasPrefix(key, "season/") && isNumericID(strings.TrimPrefix(key, "season/")):
+				targets = append(targets, target{relPath: path.Join(key, "details.json"), respKey: key})
+			}
+		}
+	case req.relDir == "" && req.resource == serveFileResources[req.mediaType]:
+		targets = append(targets, target{relPath: req.resource + ".json"})
+	case serveAppendKeys[req.resource]:
+		targets = append(targets, target{relPath: details, patchKey: req.resource})
+	default:
+		return nil, false, nil
+	}
+
+	var resp map[string]interface{}
+	changed := false
+	for _, t := range targets {
+		patch, err := f.curatedPatch(req.mediaType, req.mediaID, t.relPath)
+		if err != nil {
+			return nil, false, err
+		}
+		if t.patchKey != "" {
+			patch, _ = patch[t.patchKey].(map[string]interface{})
+		}
+		if resp == nil {
+			decoder := json.NewDecoder(bytes.NewReader(body))
+			if err := decoder.Decode(&resp); err != nil {
+				return nil, false, fmt.Errorf("解析TMDB响应失败: %v", err)
+			}
+		}
+
+		filtered := make(map[string]interface{}, len(patch))
+		for k, v := range patch {
+			switch {
+			case t.respKey != "" && (k == "id" || serveAppendKeys[k]):
+				// 附加到响应中的文件不包含ID和它自己的附加数据
+				continue
+			case t.respKey == "" && t.patchKey == "" && serveAppendKeys[k]:
+				// 附加数据只在客户端请求了时才合并
+				if _, ok := resp[k]; !ok {
+					continue
+				}
+			}
+			filtered[k] = v
+		}
+		if len(filtered) == 0 {
+			continue
+		}
+
+		if t.respKey == "" {
+			resp = mergePatch(resp, filtered).(map[string]interface{})
+		} else if _, ok := resp[t.respKey]; ok {
+			resp[t.respKey] = mergePatch(resp[t.respKey], filtered)
+		} else {
+			continue
+		}
+		changed = true
+	}
+	return resp, changed, nil
+}
+
+// serveProxy 启动代理服务，直到出错才返回
+func (f *TMDBFetcher) serveProxy(addr, cacheDir string, cacheTTL time.Duration) error {
+	if !checkDirectoryExists(f.outputDir) {
+		return fmt.Errorf("目录不存在: %s", f.outputDir)
+	}
+	upstream, err := url.Parse(f.baseURL)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("正在代理 %s，合并 %s 中维护的数据: http://%s/3\n", upstream.Host, f.outputDir, addr)
+	if cacheDir != "" {
+		fmt.Printf("TMDB响应缓存在 %s，有效期 %v\n", cacheDir, cacheTTL)
+	}
+	fmt.Printf("没有指定 language 的请求按 %s 请求TMDB，只有该语言的请求会合并维护的数据。按 Ctrl+C 停止\n", f.config.Language)
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           &proxyServer{fetcher: f, cacheDir: cacheDir, cacheTTL: cacheTTL},
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return server.ListenAndServe()
+}
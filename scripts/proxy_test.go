@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestProxyMergesCuratedLanguage(t *testing.T) {
+	var mu sync.Mutex
+	var languages []string // TMDB收到的 language 参数
+	f := newTestFetcher(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		language := r.URL.Query().Get("language")
+		mu.Lock()
+		languages = append(languages, language)
+		mu.Unlock()
+		title := "The Wandering Earth II"
+		if language == "zh-CN" {
+			title = "流浪地球2：TMDB"
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": 842675, "title": title, "vote_average": 7.2})
+	}))
+	if err := saveMediaFiles(mediaFiles{
+		"details.json": {"id": 842675, "title": "流浪地球2", "vote_average": 6.0},
+	}, f.mediaDir("movie", "842675")); err != nil {
+		t.Fatal(err)
+	}
+	proxy := &proxyServer{fetcher: f}
+
+	tests := []struct {
+		name         string
+		query        string
+		wantLanguage string
+		wantTitle    string
+	}{
+		{name: "配置的语言", query: "?language=zh-CN", wantLanguage: "zh-CN", wantTitle: "流浪地球2"},
+		{name: "没有指定语言时使用配置的语言", query: "", wantLanguage: "zh-CN", wantTitle: "流浪地球2"},
+		{name: "其他语言不合并", query: "?language=en-US", wantLanguage: "en-US", wantTitle: "The Wandering Earth II"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			languages = nil
+			rec := httptest.NewRecorder()
+			proxy.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/3/movie/842675"+tt.query, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("状态码 = %d: %s", rec.Code, rec.Body)
+			}
+			var resp map[string]interface{}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if len(languages) != 1 || languages[0] != tt.wantLanguage {
+				t.Errorf("TMDB收到的 language = %v, 期望 %s", languages, tt.wantLanguage)
+			}
+			if resp["title"] != tt.wantTitle {
+				t.Errorf("title = %v, 期望 %s", resp["title"], tt.wantTitle)
+			}
+			// 没有快照的条目只合并文本字段，评分使用TMDB的数据
+			if resp["vote_average"] != 7.2 {
+				t.Errorf("vote_average = %v, 期望 7.2", resp["vote_average"])
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateLimiter 请求速率限制器，可被多个goroutine共享
+// 保证相邻两次请求的间隔不小于 interval；收到429时可通过 pause 让所有请求一起暂停
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time // 下一个请求最早可发出的时间
+}
+
+// newRateLimiter 创建速率限制器，rps <= 0 表示不限速
+func newRateLimiter(rps float64) *rateLimiter {
+	limiter := &rateLimiter{}
+	if rps > 0 {
+		limiter.interval = time.Duration(float64(time.Second) / rps)
+	}
+	return limiter
+}
+
+// wait 阻塞直到允许发出下一个请求
+func (l *rateLimiter) wait() {
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	time.Sleep(time.Until(at))
+}
+
+// pause 在接下来的 d 时间内暂停发出新请求
+func (l *rateLimiter) pause(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	until := time.Now().Add(d)
+	if until.After(l.next) {
+		l.next = until
+	}
+}
+
+// parseRetryAfter 解析 Retry-After 响应头（秒数或HTTP日期），无法解析时默认等待1秒
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return time.Second
+}
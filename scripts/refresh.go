@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"tmdb-manager/updater"
+)
+
+// refreshFileResult 刷新时单个文件的处理结果
+type refreshFileResult struct {
+	relPath   string
+	status    string // "新增"、"更新"、"无变化"
+	conflicts []mergeConflict
+}
+
+// refreshMedia 重新获取TMDB数据，与快照和本地维护的数据进行三方合并
+// 本地修改过的字段全部保留，TMDB新增/修改的字段被合并进来，双方冲突的字段保留本地值并报告
+func (f *TMDBFetcher) refreshMedia(mediaType, mediaID string) (results []refreshFileResult, err error) {
+	label := mediaLabel(mediaType)
+	fmt.Printf("\n开始刷新%s ID: %s 的数据...\n", label, mediaID)
+
+	baseDir := f.mediaDir(mediaType, mediaID)
+	if !checkDirectoryExists(baseDir) {
+		return nil, fmt.Errorf("目录不存在: %s，请先获取该%s的数据", baseDir, label)
+	}
+	if f.hasOverlay(mediaType, mediaID) {
+		return nil, fmt.Errorf("%s/%s 使用覆盖文件维护，覆盖内容总是应用在最新的TMDB数据上，无需刷新", mediaType, mediaID)
+	}
+
+	remoteFiles, err := f.fetchMediaFiles(mediaType, mediaID)
+	if err != nil {
+		return nil, err
+	}
+	printSuspicions(mediaType, mediaID, detectVandalism(mediaType, remoteFiles))
+
+	paths := make([]string, 0, len(remoteFiles))
+	for p := range remoteFiles {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	// 先完成全部合并，确认没有读取错误后再写入
+	merged := make(mediaFiles)
+	missingSnapshot := false
+	unresolved := make(map[string]bool) // 没有快照且有冲突的文件
+
+	for _, relPath := range paths {
+		remote := remoteFiles[relPath]
+		localPath := filepath.Join(baseDir, filepath.FromSlash(relPath))
+
+		local, err := loadJSON(localPath)
+		if os.IsNotExist(err) {
+			// TMDB新增的文件 (如新的一季)，直接保存
+			merged[relPath] = remote
+			results = append(results, refreshFileResult{relPath: relPath, status: "新增"})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		snapshot, err := f.loadSnapshotFile(mediaType, mediaID, relPath)
+		if err != nil {
+			return nil, err
+		}
+		var base interface{} = absent
+		if snapshot != nil {
+			base = snapshot
+		} else {
+			missingSnapshot = true
+		}
+
+		var conflicts []mergeConflict
+		result := merge3("", base, local, remote, &conflicts)
+		resultMap, _ := result.(map[string]interface{})
+
+		status := "无变化"
+		if !reflect.DeepEqual(resultMap, local) {
+			merged[relPath] = resultMap
+			status = "更新"
+		}
+		results = append(results, refreshFileResult{relPath: relPath, status: status, conflicts: conflicts})
+		if snapshot == nil && len(conflicts) > 0 {
+			unresolved[relPath] = true
+		}
+	}
+
+	if len(merged) > 0 {
+		if err := replaceMediaFiles(f.outputDir, mediaType, mediaID, baseDir, merged); err != nil {
+			return nil, err
+		}
+	}
+
+	// 更新快照，下次刷新以本次的TMDB数据为基准
+	// 没有快照的文件中冲突的字段保留了本地值，如果以本次的TMDB数据为快照，下次刷新会把这些字段当作本地修改，
+	// 不再报告冲突，因此这些文件不保存快照，直到冲突处理完、刷新时不再有冲突
+	snapshotFiles := make(mediaFiles, len(remoteFiles))
+	for relPath, data := range remoteFiles {
+		if !unresolved[relPath] {
+			snapshotFiles[relPath] = data
+		}
+	}
+	if len(snapshotFiles) > 0 {
+		if err := f.saveSnapshot(mediaType, mediaID, snapshotFiles); err != nil {
+			fmt.Printf("⚠️  保存TMDB原始数据快照失败: %v\n", err)
+		}
+	}
+
+	if missingSnapshot {
+		fmt.Println("\n⚠️  部分文件没有TMDB原始数据快照，无法区分本地修改和TMDB修改，")
+		fmt.Println("   两边不同的字段都保留了本地值并列为冲突。")
+		if len(unresolved) > 0 {
+			fmt.Println("   有冲突的文件未保存快照，下次刷新仍会列出这些冲突，刷新时没有冲突后才会保存快照。")
+		} else {
+			fmt.Println("   已保存本次快照，下次刷新将精确合并。")
+		}
+	}
+
+	return results, nil
+}
+
+// replaceMediaFiles 在暂存目录中的条目副本上写入修改的文件，全部成功后整体替换条目目录
+// 写入中途失败时条目目录保持原样，不会留下只合并了一部分的数据
+func replaceMediaFiles(rootDir, mediaType, mediaID, baseDir string, files mediaFiles) (err error) {
+	stagingDir, err := newStagingDir(rootDir, mediaType, mediaID)
+	if err != nil {
+		return err
+	}
+	defer discardStagingDir(stagingDir, &err)
+
+	if err := updater.CopyDir(baseDir, stagingDir); err != nil {
+		return err
+	}
+	if err := saveMediaFiles(files, stagingDir); err != nil {
+		return err
+	}
+	return replaceWithStagingDir(stagingDir, baseDir)
+}
+
+// printRefreshReport 打印刷新结果和冲突，返回冲突数量
+func printRefreshReport(mediaType, mediaID string, results []refreshFileResult) int {
+	total := 0
+	fmt.Printf("\n✓ %s %s 刷新完成\n", mediaLabel(mediaType), mediaID)
+	for _, r := range results {
+		fmt.Printf("  %-6s %s", r.status, r.relPath)
+		if len(r.conflicts) > 0 {
+			fmt.Printf("  (%d 处冲突)", len(r.conflicts))
+		}
+		fmt.Println()
+		total += len(r.conflicts)
+	}
+
+	if total == 0 {
+		return 0
+	}
+
+	fmt.Println("\n" + strings.Repeat("=", 60))
+	fmt.Printf("⚠️  %d 处冲突需要手动处理（已保留本地值）:\n", total)
+	fmt.Println(strings.Repeat("=", 60))
+	for _, r := range results {
+		for _, c := range r.conflicts {
+			fmt.Printf("\n%s: %s\n", r.relPath, c.path)
+			fmt.Printf("  快照: %s\n", formatValue(c.base))
+			fmt.Printf("  本地: %s\n", formatValue(c.local))
+			fmt.Printf("  TMDB: %s\n", formatValue(c.remote))
+		}
+	}
+	return total
+}
+
+// formatValue 将JSON值格式化为单行文本，过长时截断
+func formatValue(v interface{}) string {
+	if v == absent {
+		return "(不存在)"
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	s := string(data)
+	if runes := []rune(s); len(runes) > 120 {
+		s = string(runes[:120]) + "..."
+	}
+	return s
+}
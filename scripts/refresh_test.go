@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestFetcher 创建请求 handler 的获取器，数据目录为临时目录中的 tmdb_config
+func newTestFetcher(t *testing.T, handler http.Handler) *TMDBFetcher {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return &TMDBFetcher{
+		config:     Config{TMDBAPIKey: "test", Language: "zh-CN"},
+		httpClient: server.Client(),
+		baseURL:    server.URL,
+		outputDir:  filepath.Join(t.TempDir(), "tmdb_config"),
+		limiter:    newRateLimiter(0),
+	}
+}
+
+// jsonHandler 按请求路径返回 responses 中的JSON
+func jsonHandler(responses map[string]string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(body))
+	})
+}
+
+func TestRefreshWithoutSnapshotKeepsConflicts(t *testing.T) {
+	f := newTestFetcher(t, jsonHandler(map[string]string{
+		"/movie/1":               `{"id": 1, "title": "TMDB的标题", "runtime": 120}`,
+		"/movie/1/release_dates": `{"id": 1, "results": []}`,
+	}))
+	if err := saveMediaFiles(mediaFiles{
+		"details.json":       {"id": 1, "title": "本地的标题", "runtime": 120},
+		"release_dates.json": {"id": 1, "results": []interface{}{}},
+	}, f.mediaDir("movie", "1")); err != nil {
+		t.Fatal(err)
+	}
+
+	// 没有快照时两次刷新都应报告同一个冲突，不能因为第一次保存了快照而把本地值当作维护者的修改
+	for i := 1; i <= 2; i++ {
+		results, err := f.refreshMedia("movie", "1")
+		if err != nil {
+			t.Fatalf("第 %d 次刷新失败: %v", i, err)
+		}
+		conflicts := map[string]int{}
+		for _, r := range results {
+			conflicts[r.relPath] = len(r.conflicts)
+		}
+		if conflicts["details.json"] != 1 || conflicts["release_dates.json"] != 0 {
+			t.Fatalf("第 %d 次刷新的冲突 = %v, 期望 details.json 有 1 处冲突", i, conflicts)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(f.snapshotDir("movie", "1"), "details.json")); !os.IsNotExist(err) {
+		t.Errorf("有冲突的 details.json 不应保存快照")
+	}
+	if snapshot, err := f.loadSnapshotFile("movie", "1", "release_dates.json"); err != nil || snapshot == nil {
+		t.Errorf("没有冲突的 release_dates.json 应保存快照: %v", err)
+	}
+	local, err := loadJSON(filepath.Join(f.mediaDir("movie", "1"), "details.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if local["title"] != "本地的标题" {
+		t.Errorf("冲突的字段应保留本地值, title = %v", local["title"])
+	}
+}
+
+func TestRefreshWithSnapshotMergesRemoteChanges(t *testing.T) {
+	f := newTestFetcher(t, jsonHandler(map[string]string{
+		"/movie/1":               `{"id": 1, "title": "TMDB的标题", "runtime": 125}`,
+		"/movie/1/release_dates": `{"id": 1, "results": []}`,
+	}))
+	if err := saveMediaFiles(mediaFiles{
+		"details.json":       {"id": 1, "title": "本地的标题", "runtime": 120},
+		"release_dates.json": {"id": 1, "results": []interface{}{}},
+	}, f.mediaDir("movie", "1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.saveSnapshot("movie", "1", mediaFiles{
+		"details.json":       {"id": 1, "title": "TMDB的标题", "runtime": 120},
+		"release_dates.json": {"id": 1, "results": []interface{}{}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := f.refreshMedia("movie", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range results {
+		if len(r.conflicts) > 0 {
+			t.Errorf("%s 不应有冲突: %+v", r.relPath, r.conflicts)
+		}
+	}
+	local, err := loadJSON(filepath.Join(f.mediaDir("movie", "1"), "details.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := json.Marshal(local); string(got) != `{"id":1,"runtime":125,"title":"本地的标题"}` {
+		t.Errorf("合并后的 details.json = %s", got)
+	}
+	snapshot, err := f.loadSnapshotFile("movie", "1", "details.json")
+	if err != nil || snapshot["runtime"] != float64(125) {
+		t.Errorf("快照应更新为本次获取的TMDB数据: %v, %v", snapshot, err)
+	}
+}
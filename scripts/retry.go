@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// 错误分类，可通过 errors.Is 判断
+var (
+	errNotFound     = errors.New("TMDB中不存在该条目")
+	errUnauthorized = errors.New("TMDB API Key无效或没有权限")
+	errRateLimited  = errors.New("TMDB请求过于频繁")
+)
+
+// 重试退避参数，测试中会缩短
+var (
+	retryBaseDelay = time.Second
+	retryMaxDelay  = 30 * time.Second
+)
+
+// apiError TMDB返回的非200响应
+type apiError struct {
+	statusCode int
+	endpoint   string
+	body       string
+	retryAfter time.Duration // 429 响应中 Retry-After 指定的等待时间
+}
+
+func (e *apiError) Error() string {
+	if kind := e.Unwrap(); kind != nil {
+		return fmt.Sprintf("%v (%d): %s", kind, e.statusCode, e.endpoint)
+	}
+	return fmt.Sprintf("API返回错误 %d: %s", e.statusCode, e.body)
+}
+
+// Unwrap 将状态码映射到错误分类
+func (e *apiError) Unwrap() error {
+	switch e.statusCode {
+	case http.StatusNotFound:
+		return errNotFound
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return errUnauthorized
+	case http.StatusTooManyRequests:
+		return errRateLimited
+	}
+	return nil
+}
+
+// temporary 是否为可重试的临时错误 (429, 502, 503, 504)
+func (e *apiError) temporary() bool {
+	switch e.statusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// requestError 发送请求或读取响应失败
+type requestError struct {
+	op  string // 失败的步骤，如 "请求失败"、"解析响应失败"
+	err error
+}
+
+func (e *requestError) Error() string {
+	// url.Error 的信息中包含带 api_key 的完整URL，只保留底层错误
+	var urlErr *url.Error
+	if errors.As(e.err, &urlErr) {
+		return fmt.Sprintf("%s: %v", e.op, urlErr.Err)
+	}
+	return fmt.Sprintf("%s: %v", e.op, e.err)
+}
+
+func (e *requestError) Unwrap() error {
+	return e.err
+}
+
+// isRetryable 判断错误是否值得自动重试
+func isRetryable(err error) bool {
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		return apiErr.temporary()
+	}
+
+	var reqErr *requestError
+	if !errors.As(err, &reqErr) {
+		return false
+	}
+
+	// 超时、连接被重置/中断、响应被截断
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// retryBackoff 返回第 attempt 次重试前的等待时间
+// 指数增长 (1s, 2s, 4s...) 并在 [d/2, d) 范围内随机抖动，避免并发请求同时重试
+func retryBackoff(attempt int) time.Duration {
+	d := retryBaseDelay << uint(attempt-1)
+	if d <= 0 || d > retryMaxDelay {
+		d = retryMaxDelay
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)))
+}
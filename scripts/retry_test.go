@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// statusHandler 按顺序返回 statuses 中的状态码，之后返回 200 和一个JSON对象，记录请求次数
+func statusHandler(requests *int32, statuses []int, header http.Header) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(requests, 1))
+		if n <= len(statuses) {
+			for key, values := range header {
+				w.Header()[key] = values
+			}
+			w.WriteHeader(statuses[n-1])
+			w.Write([]byte(`{"status_message": "error"}`))
+			return
+		}
+		w.Write([]byte(`{"id": 1}`))
+	})
+}
+
+// shortenRetryDelay 缩短重试的等待时间
+func shortenRetryDelay(t *testing.T) {
+	base, max := retryBaseDelay, retryMaxDelay
+	retryBaseDelay, retryMaxDelay = time.Millisecond, 10*time.Millisecond
+	t.Cleanup(func() { retryBaseDelay, retryMaxDelay = base, max })
+}
+
+func TestMakeRequestErrors(t *testing.T) {
+	shortenRetryDelay(t)
+	tests := []struct {
+		name         string
+		status       int
+		wantErr      error  // 错误分类
+		wantMessage  string // 没有分类时错误信息中应包含的内容
+		wantRequests int32
+	}{
+		{name: "404", status: http.StatusNotFound, wantErr: errNotFound, wantRequests: 1},
+		{name: "401", status: http.StatusUnauthorized, wantErr: errUnauthorized, wantRequests: 1},
+		{name: "403", status: http.StatusForbidden, wantErr: errUnauthorized, wantRequests: 1},
+		{name: "500 不重试", status: http.StatusInternalServerError, wantMessage: "API返回错误 500", wantRequests: 1},
+		{name: "503 重试到上限", status: http.StatusServiceUnavailable, wantMessage: "API返回错误 503", wantRequests: 3},
+		{name: "429 重试到上限", status: http.StatusTooManyRequests, wantErr: errRateLimited, wantRequests: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int32
+			f := newTestFetcher(t, statusHandler(&requests, []int{tt.status, tt.status, tt.status, tt.status}, nil))
+			f.config.MaxRetries = 2
+
+			_, err := f.makeRequest("/movie/1", nil)
+			if err == nil {
+				t.Fatal("makeRequest() 应返回错误")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("错误 = %v, 期望 %v", err, tt.wantErr)
+			}
+			if tt.wantMessage != "" && !strings.Contains(err.Error(), tt.wantMessage) {
+				t.Errorf("错误 = %v, 期望包含 %q", err, tt.wantMessage)
+			}
+			if strings.Contains(err.Error(), "api_key") {
+				t.Errorf("错误信息中不应包含 api_key: %v", err)
+			}
+			if requests != tt.wantRequests {
+				t.Errorf("请求次数 = %d, 期望 %d", requests, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestMakeRequestRetriesTemporaryErrors(t *testing.T) {
+	shortenRetryDelay(t)
+	var requests int32
+	f := newTestFetcher(t, statusHandler(&requests, []int{http.StatusBadGateway, http.StatusGatewayTimeout}, nil))
+	f.config.MaxRetries = 2
+
+	result, err := f.makeRequest("/movie/1", nil)
+	if err != nil || result["id"] != float64(1) {
+		t.Fatalf("makeRequest() = %v, %v", result, err)
+	}
+	if requests != 3 {
+		t.Errorf("请求次数 = %d, 期望 3", requests)
+	}
+
+	// 不重试时第一次失败就返回
+	requests = 0
+	f.config.MaxRetries = 0
+	if _, err := f.makeRequest("/movie/1", nil); err == nil || requests != 1 {
+		t.Errorf("MaxRetries 为 0 时 makeRequest() = %v, 请求次数 %d, 期望失败且只请求 1 次", err, requests)
+	}
+}
+
+func TestMakeRequestHonorsRetryAfter(t *testing.T) {
+	shortenRetryDelay(t)
+	var requests int32
+	f := newTestFetcher(t, statusHandler(&requests, []int{http.StatusTooManyRequests}, http.Header{"Retry-After": {"1"}}))
+	f.config.MaxRetries = 1
+
+	start := time.Now()
+	if _, err := f.makeRequest("/movie/1", nil); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("重试前等待了 %v, 期望按 Retry-After 等待 1s", elapsed)
+	}
+	if requests != 2 {
+		t.Errorf("请求次数 = %d, 期望 2", requests)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// searchResult 搜索结果中的一个候选条目
+type searchResult struct {
+	id               string
+	title            string
+	originalTitle    string
+	originalLanguage string
+	year             string
+}
+
+// searchPage 一页搜索结果
+type searchPage struct {
+	page       int
+	totalPages int
+	results    []searchResult
+}
+
+// searchMedia 按标题搜索电影/电视剧，page 从1开始
+func (f *TMDBFetcher) searchMedia(mediaType, query string, page int) (*searchPage, error) {
+	endpoint := "/search/" + mediaType
+	params := map[string]string{
+		"query":         query,
+		"page":          strconv.Itoa(page),
+		"include_adult": "false",
+	}
+	data, err := f.makeRequest(endpoint, params)
+	if err != nil {
+		return nil, err
+	}
+
+	titleKey, originalKey, dateKey := "name", "original_name", "first_air_date"
+	if mediaType == "movie" {
+		titleKey, originalKey, dateKey = "title", "original_title", "release_date"
+	}
+
+	result := &searchPage{page: page}
+	if n, ok := data["total_pages"].(float64); ok {
+		result.totalPages = int(n)
+	}
+	items, _ := data["results"].([]interface{})
+	for _, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		id, ok := m["id"].(float64)
+		if !ok {
+			continue
+		}
+		r := searchResult{id: strconv.FormatFloat(id, 'f', -1, 64)}
+		r.title, _ = m[titleKey].(string)
+		r.originalTitle, _ = m[originalKey].(string)
+		r.originalLanguage, _ = m["original_language"].(string)
+		if date, _ := m[dateKey].(string); len(date) >= 4 {
+			r.year = date[:4]
+		}
+		result.results = append(result.results, r)
+	}
+	return result, nil
+}
+
+// printSearchResults 打印带序号的候选列表
+func printSearchResults(results []searchResult) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  序号\t年份\t标题\t原始标题\t原始语言\tTMDB ID")
+	for i, r := range results {
+		year := r.year
+		if year == "" {
+			year = "-"
+		}
+		fmt.Fprintf(w, "  %d\t%s\t%s\t%s\t%s\t%s\n", i+1, year, r.title, r.originalTitle, r.originalLanguage, r.id)
+	}
+	w.Flush()
+}
+
+// chooseSearchResult 按关键字搜索并让用户从候选列表中选择，返回选中的TMDB ID
+// 用户放弃选择时返回空字符串
+func (f *TMDBFetcher) chooseSearchResult(reader *bufio.Reader, mediaType, query string) (string, error) {
+	page := 1
+	for {
+		fmt.Printf("\n正在搜索%s: %s\n", mediaLabel(mediaType), query)
+		result, err := f.searchMedia(mediaType, query, page)
+		if err != nil {
+			return "", err
+		}
+		if len(result.results) == 0 {
+			fmt.Println("没有找到匹配的结果，请换个关键字试试")
+			return "", nil
+		}
+
+		fmt.Printf("\n搜索结果 (第 %d/%d 页):\n", result.page, result.totalPages)
+		printSearchResults(result.results)
+
+		hasNext := result.page < result.totalPages
+		for {
+			if hasNext {
+				fmt.Print("\n请输入序号选择 (n 下一页, 直接回车重新输入): ")
+			} else {
+				fmt.Print("\n请输入序号选择 (直接回车重新输入): ")
+			}
+
+			input, err := reader.ReadString('\n')
+			if err != nil {
+				return "", err
+			}
+			input = strings.TrimSpace(strings.ToLower(input))
+
+			if input == "" {
+				return "", nil
+			}
+			if input == "n" && hasNext {
+				page++
+				break
+			}
+			n, err := strconv.Atoi(input)
+			if err != nil || n < 1 || n > len(result.results) {
+				fmt.Println("无效的序号，请重新输入")
+				continue
+			}
+
+			chosen := result.results[n-1]
+			fmt.Printf("已选择: %s", chosen.title)
+			if chosen.year != "" {
+				fmt.Printf(" (%s)", chosen.year)
+			}
+			fmt.Printf(" TMDB ID: %s\n", chosen.id)
+			return chosen.id, nil
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// defaultServeAddr serve 命令默认的监听地址
+const defaultServeAddr = "127.0.0.1:8080"
+
+// serveAppendKeys details.json 中通过 append_to_response 附加的数据，与获取时的请求一致
+// 客户端请求中没有列出的不会返回，与TMDB的行为相同
+var serveAppendKeys = map[string]bool{
+	"credits":            true,
+	"aggregate_credits":  true,
+	"alternative_titles": true,
+	"translations":       true,
+	"external_ids":       true,
+}
+
+// serveFileResources 单独保存为文件的子资源，如 /movie/{id}/release_dates
+var serveFileResources = map[string]string{
+	"movie": "release_dates",
+	"tv":    "content_ratings",
+}
+
+// tmdbStatus TMDB错误响应，字段与官方API一致
+type tmdbStatus struct {
+	httpStatus    int
+	statusCode    int
+	statusMessage string
+}
+
+var (
+	statusNotFound = tmdbStatus{http.StatusNotFound, 34, "The resource you requested could not be found."}
+	statusInternal = tmdbStatus{http.StatusInternalServerError, 11, "Internal error: Something went wrong, contact TMDB."}
+)
+
+// serveRequest 解析后的请求路径
+type serveRequest struct {
+	mediaType string
+	mediaID   string
+	relDir    string // 数据文件所在的相对目录，如 ""、"season/1"、"season/1/episode/2"
+	resource  string // 子资源，如 credits、release_dates，为空表示详情
+}
+
+// parseServePath 解析 /3/ 之后的路径，支持:
+//
+//	movie/{id}[/子资源]
+//	tv/{id}[/子资源]
+//	tv/{id}/season/{n}[/子资源]
+//	tv/{id}/season/{n}/episode/{e}[/子资源]
+func parseServePath(p string) (serveRequest, bool) {
+	parts := strings.Split(strings.Trim(p, "/"), "/")
+	if len(parts) < 2 || (parts[0] != "movie" && parts[0] != "tv") || !isNumericID(parts[1]) {
+		return serveRequest{}, false
+	}
+	req := serveRequest{mediaType: parts[0], mediaID: parts[1]}
+	rest := parts[2:]
+
+	if req.mediaType == "tv" && len(rest) >= 2 && rest[0] == "season" && isNumericID(rest[1]) {
+		req.relDir = path.Join("season", rest[1])
+		rest = rest[2:]
+		if len(rest) >= 2 && rest[0] == "episode" && isNumericID(rest[1]) {
+			req.relDir = path.Join(req.relDir, "episode", rest[1])
+			rest = rest[2:]
+		}
+	}
+	switch len(rest) {
+	case 0:
+		return req, true
+	case 1:
+		req.resource = rest[0]
+		return req, true
+	}
+	return serveRequest{}, false
+}
+
+// loadCuratedFile 读取条目中维护的数据文件，文件不存在时返回 nil
+// 使用覆盖文件的条目以 tmdb_upstream 中的快照为基础应用覆盖内容
+func (f *TMDBFetcher) loadCuratedFile(mediaType, mediaID, relPath string) (map[string]interface{}, error) {
+	if !f.hasOverlay(mediaType, mediaID) {
+		data, err := loadJSON(filepath.Join(f.mediaDir(mediaType, mediaID), filepath.FromSlash(relPath)))
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return data, err
+	}
+
+	if !checkDirectoryExists(f.snapshotDir(mediaType, mediaID)) {
+		return nil, fmt.Errorf("%s/%s 使用覆盖文件维护，但 %s 中没有TMDB原始数据快照，无法应用覆盖文件", mediaType, mediaID, upstreamDirName)
+	}
+	o, err := f.loadOverlay(mediaType, mediaID)
+	if err != nil {
+		return nil, err
+	}
+	patch, patched := o[relPath]
+	if patched && patch == nil {
+		return nil, nil
+	}
+	base, err := f.loadSnapshotFile(mediaType, mediaID, relPath)
+	if err != nil {
+		return nil, err
+	}
+	if !patched {
+		return base, nil
+	}
+	merged, _ := mergePatch(base, patch).(map[string]interface{})
+	return merged, nil
+}
+
+// buildServeResponse 按请求组装TMDB格式的响应，资源不存在时返回 nil
+func (f *TMDBFetcher) buildServeResponse(req serveRequest, appendTo []string) (map[string]interface{}, error) {
+	// 电影的发行日期、电视剧的内容分级单独保存为文件
+	if req.relDir == "" && req.resource != "" && req.resource == serveFileResources[req.mediaType] {
+		return f.loadCuratedFile(req.mediaType, req.mediaID, req.resource+".json")
+	}
+
+	details, err := f.loadCuratedFile(req.mediaType, req.mediaID, path.Join(req.relDir, "details.json"))
+	if err != nil || details == nil {
+		return nil, err
+	}
+
+	if req.resource != "" {
+		sub, ok := details[req.resource].(map[string]interface{})
+		if !serveAppendKeys[req.resource] || !ok {
+			return nil, nil
+		}
+		result := make(map[string]interface{}, len(sub)+1)
+		for k, v := range sub {
+			result[k] = v
+		}
+		result["id"] = details["id"]
+		return result, nil
+	}
+
+	requested := make(map[string]bool, len(appendTo))
+	for _, key := range appendTo {
+		requested[key] = true
+	}
+	result := make(map[string]interface{}, len(details))
+	for k, v := range details {
+		if serveAppendKeys[k] && !requested[k] {
+			continue
+		}
+		result[k] = v
+	}
+
+	for _, key := range appendTo {
+		var data map[string]interface{}
+		switch {
+		case req.relDir == "" && key == serveFileResources[req.mediaType]:
+			data, err = f.loadCuratedFile(req.mediaType, req.mediaID, key+".json")
+			if data != nil {
+				// 附加到详情中时不包含ID，与TMDB一致
+				data = mergePatch(data, map[string]interface{}{"id": nil}).(map[string]interface{})
+			}
+		case req.mediaType == "tv" && req.relDir == "" && strings.HasPrefix(key, "season/"):
+			if !isNumericID(strings.TrimPrefix(key, "season/")) {
+				continue
+			}
+			data, err = f.loadCuratedFile(req.mediaType, req.mediaID, path.Join(key, "details.json"))
+			if data != nil {
+				data = mergePatch(data, stripAppendKeys(data)).(map[string]interface{})
+			}
+		default:
+			// 未知的附加数据与TMDB一样直接忽略
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if data != nil {
+			result[key] = data
+		}
+	}
+	return result, nil
+}
+
+// stripAppendKeys 返回删除全部附加数据的 Merge Patch
+func stripAppendKeys(data map[string]interface{}) map[string]interface{} {
+	patch := make(map[string]interface{})
+	for key := range serveAppendKeys {
+		if _, ok := data[key]; ok {
+			patch[key] = nil
+		}
+	}
+	return patch
+}
+
+// configServer 以TMDB API的格式提供 tmdb_config 中的数据
+type configServer struct {
+	local *TMDBFetcher // 只用于读取本地数据，不需要API Key
+}
+
+func (s *configServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := s.serve(w, r)
+	fmt.Printf("%s %s %s %d %v\n", start.Format("15:04:05"), r.Method, r.URL.Path, status, time.Since(start).Round(time.Millisecond))
+}
+
+// serve 处理一个请求，返回HTTP状态码
+func (s *configServer) serve(w http.ResponseWriter, r *http.Request) int {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return http.StatusMethodNotAllowed
+	}
+
+	req, ok := parseServePath(strings.TrimPrefix(r.URL.Path, "/3/"))
+	if !ok || !strings.HasPrefix(r.URL.Path, "/3/") {
+		return writeTMDBStatus(w, statusNotFound)
+	}
+
+	var appendTo []string
+	for _, key := range strings.Split(r.URL.Query().Get("append_to_response"), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			appendTo = append(appendTo, key)
+		}
+	}
+
+	data, err := s.local.buildServeResponse(req, appendTo)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "错误: %s: %v\n", r.URL.Path, err)
+		return writeTMDBStatus(w, statusInternal)
+	}
+	if data == nil {
+		return writeTMDBStatus(w, statusNotFound)
+	}
+	return writeJSONResponse(w, http.StatusOK, data)
+}
+
+// writeTMDBStatus 输出TMDB格式的错误响应
+func writeTMDBStatus(w http.ResponseWriter, s tmdbStatus) int {
+	return writeJSONResponse(w, s.httpStatus, map[string]interface{}{
+		"success":        false,
+		"status_code":    s.statusCode,
+		"status_message": s.statusMessage,
+	})
+}
+
+// writeJSONResponse 输出JSON响应，返回HTTP状态码
+func writeJSONResponse(w http.ResponseWriter, status int, data interface{}) int {
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(data); err != nil {
+		fmt.Fprintf(os.Stderr, "写入响应失败: %v\n", err)
+	}
+	return status
+}
+
+// serveConfig 启动本地HTTP服务，直到出错才返回
+func serveConfig(outputDir, addr string) error {
+	if !checkDirectoryExists(outputDir) {
+		return fmt.Errorf("目录不存在: %s", outputDir)
+	}
+	fmt.Printf("正在提供 %s 中的数据: http://%s/3\n", outputDir, addr)
+	fmt.Println("将TMDB客户端的API地址改为上面的地址即可，api_key 可以任意填写。按 Ctrl+C 停止")
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           &configServer{local: &TMDBFetcher{outputDir: outputDir}},
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return server.ListenAndServe()
+}
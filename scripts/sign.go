@@ -0,0 +1,207 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"tmdb-manager/updater"
+)
+
+// 签名相关的约定
+const (
+	publicKeyExt  = ".pub"             // 生成密钥时公钥文件的扩展名
+	signingKeyEnv = "TMDB_SIGNING_KEY" // 未指定私钥文件时从该环境变量读取私钥
+)
+
+// generateSigningKey 生成 ed25519 密钥对，私钥写入 privPath (权限 0600)，公钥写入 privPath.pub
+// 私钥和公钥均为 base64 编码，私钥为 32 字节的种子
+func generateSigningKey(privPath string) (ed25519.PublicKey, error) {
+	if _, err := os.Stat(privPath); err == nil {
+		return nil, fmt.Errorf("%s 已存在，不会覆盖已有的私钥", privPath)
+	}
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("生成密钥失败: %v", err)
+	}
+	if err := os.WriteFile(privPath, []byte(base64.StdEncoding.EncodeToString(priv.Seed())+"\n"), 0600); err != nil {
+		return nil, fmt.Errorf("保存私钥失败: %v", err)
+	}
+	if err := os.WriteFile(privPath+publicKeyExt, []byte(base64.StdEncoding.EncodeToString(pub)+"\n"), 0644); err != nil {
+		return nil, fmt.Errorf("保存公钥失败: %v", err)
+	}
+	return pub, nil
+}
+
+// loadPrivateKey 读取私钥文件，path 为空时从环境变量 TMDB_SIGNING_KEY 读取
+func loadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	var encoded string
+	if path == "" {
+		encoded = os.Getenv(signingKeyEnv)
+		if encoded == "" {
+			return nil, fmt.Errorf("请使用 --key 指定私钥文件，或设置环境变量 %s", signingKeyEnv)
+		}
+	} else {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("读取私钥失败: %v", err)
+		}
+		encoded = string(data)
+	}
+	seed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("私钥格式错误，应为 base64 编码的 %d 字节 ed25519 种子", ed25519.SeedSize)
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// parsePublicKey 解析 base64 编码的公钥，value 也可以是公钥文件的路径
+func parsePublicKey(value string) (ed25519.PublicKey, error) {
+	encoded := value
+	if data, err := os.ReadFile(value); err == nil {
+		encoded = string(data)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("公钥格式错误，应为 base64 编码的 %d 字节 ed25519 公钥或公钥文件路径", ed25519.PublicKeySize)
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// writeSignature 对数据签名并写入 filePath.sig
+func writeSignature(priv ed25519.PrivateKey, filePath string, data []byte) error {
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(priv, data)) + "\n"
+	if err := writeFileAtomic(filePath+updater.SignatureExt, []byte(signature)); err != nil {
+		return fmt.Errorf("保存签名失败: %v", err)
+	}
+	fmt.Printf("已签名: %s\n", filePath)
+	return nil
+}
+
+// checkSignature 使用 filePath.sig 校验数据的签名
+func checkSignature(pub ed25519.PublicKey, filePath string, data []byte) error {
+	encoded, err := os.ReadFile(filePath + updater.SignatureExt)
+	if err != nil {
+		return fmt.Errorf("读取签名失败: %v", err)
+	}
+	if err := updater.VerifySignature(pub, data, encoded); err != nil {
+		return fmt.Errorf("%s: %v", filepath.Base(filePath), err)
+	}
+	return nil
+}
+
+// signRelease 对 dir 中的发布包签名
+// 先把公钥指纹写入 manifest.json，再分别对压缩包、增量包 (如有) 和 manifest.json 签名
+func signRelease(dir string, priv ed25519.PrivateKey) (string, error) {
+	archivePath := filepath.Join(dir, packArchiveName)
+	manifestPath := filepath.Join(dir, updater.ManifestName)
+
+	archive, err := os.ReadFile(archivePath)
+	if err != nil {
+		return "", fmt.Errorf("读取压缩包失败: %v", err)
+	}
+	manifest, err := updater.LoadManifest(manifestPath)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(archive)
+	if manifest.SHA256 != hex.EncodeToString(sum[:]) {
+		return "", fmt.Errorf("%s 与 %s 中记录的 SHA256 不一致，请重新运行 pack", packArchiveName, updater.ManifestName)
+	}
+
+	fingerprint := updater.Fingerprint(priv.Public().(ed25519.PublicKey))
+	manifest.SigningKey = fingerprint
+	if err := saveJSON(manifest, manifestPath); err != nil {
+		return "", err
+	}
+	manifestData, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return "", err
+	}
+
+	if err := writeSignature(priv, archivePath, archive); err != nil {
+		return "", err
+	}
+	if manifest.Delta != nil {
+		deltaPath := filepath.Join(dir, manifest.Delta.Archive)
+		delta, err := os.ReadFile(deltaPath)
+		if err != nil {
+			return "", fmt.Errorf("读取增量包失败: %v", err)
+		}
+		if err := writeSignature(priv, deltaPath, delta); err != nil {
+			return "", err
+		}
+	}
+	if err := writeSignature(priv, manifestPath, manifestData); err != nil {
+		return "", err
+	}
+	return fingerprint, nil
+}
+
+// verifyRelease 离线校验下载的压缩包或增量包: 签名、与 manifest.json 的一致性以及每个文件的 SHA256
+// 压缩包所在目录中没有 manifest.json 时只校验压缩包的签名，返回的 manifest 为 nil
+func verifyRelease(archivePath string, pub ed25519.PublicKey) (*updater.Manifest, error) {
+	archive, err := os.ReadFile(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("读取压缩包失败: %v", err)
+	}
+	if err := checkSignature(pub, archivePath, archive); err != nil {
+		return nil, err
+	}
+
+	manifestPath := filepath.Join(filepath.Dir(archivePath), updater.ManifestName)
+	manifestData, err := os.ReadFile(manifestPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSignature(pub, manifestPath, manifestData); err != nil {
+		return nil, err
+	}
+	manifest, err := updater.LoadManifest(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if manifest.SigningKey != updater.Fingerprint(pub) {
+		return nil, fmt.Errorf("%s 中的公钥指纹 %s 与使用的公钥 %s 不一致", updater.ManifestName, manifest.SigningKey, updater.Fingerprint(pub))
+	}
+	size, checksum := manifest.Size, manifest.SHA256
+	isDelta := manifest.IsDeltaArchive(archivePath)
+	if isDelta {
+		size, checksum = manifest.Delta.Size, manifest.Delta.SHA256
+	}
+	if err := updater.CheckArchive(filepath.Base(archivePath), archive, size, checksum); err != nil {
+		return nil, err
+	}
+	if err := verifyArchiveFiles(archive, manifest, isDelta); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// verifyArchiveFiles 校验压缩包中每个文件的 SHA256 与 manifest 中的记录一致
+// 完整的发布包不能有多余或缺少的文件，增量包中的文件数应与 manifest 中记录的一致
+func verifyArchiveFiles(archive []byte, manifest *updater.Manifest, isDelta bool) error {
+	files, _, err := updater.ReadArchive(archive)
+	if err != nil {
+		return err
+	}
+	problems := updater.CompareFiles(files, manifest, !isDelta)
+	if isDelta && len(files) != manifest.Delta.Changed {
+		problems = append(problems, fmt.Sprintf("增量包中有 %d 个文件，%s 中记录为 %d 个", len(files), updater.ManifestName, manifest.Delta.Changed))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("压缩包内容与 %s 不一致:\n  %s", updater.ManifestName, strings.Join(problems, "\n  "))
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// upstreamDirName TMDB原始数据快照目录名，与 tmdb_config 同级，不会被打包发布
+const upstreamDirName = "tmdb_upstream"
+
+// snapshotMetaFile 快照元信息文件名，与数据文件放在同一目录
+const snapshotMetaFile = "_meta.json"
+
+// snapshotMeta 快照的来源信息
+type snapshotMeta struct {
+	MediaType string           `json:"media_type"`
+	TMDBID    string           `json:"tmdb_id"`
+	FetchedAt string           `json:"fetched_at"` // RFC3339 UTC
+	Language  string           `json:"language"`
+	Files     []snapshotSource `json:"files"`
+}
+
+// snapshotSource 快照中单个文件对应的TMDB API请求
+type snapshotSource struct {
+	File             string `json:"file"`
+	Endpoint         string `json:"endpoint"`
+	AppendToResponse string `json:"append_to_response,omitempty"`
+}
+
+// sourceFor 返回数据文件对应的API请求
+// 目录结构与API路径一致，如 season/1/episode/2/details.json 对应 /tv/{id}/season/1/episode/2
+func sourceFor(mediaType, mediaID, relPath string) snapshotSource {
+	sub := strings.TrimSuffix(relPath, ".json")
+	sub = strings.TrimSuffix(sub, "details")
+	endpoint := strings.TrimSuffix(path.Join("/", mediaType, mediaID, sub), "/")
+
+	source := snapshotSource{File: relPath, Endpoint: endpoint}
+	switch {
+	case relPath == "details.json" && mediaType == "movie":
+		source.AppendToResponse = movieAppendToResponse
+	case relPath == "details.json":
+		source.AppendToResponse = tvAppendToResponse
+	case path.Base(relPath) == "details.json":
+		source.AppendToResponse = episodeAppendToResponse
+	}
+	return source
+}
+
+// upstreamRoot 返回快照根目录: 与输出目录同级的 tmdb_upstream
+func (f *TMDBFetcher) upstreamRoot() string {
+	return filepath.Join(filepath.Dir(filepath.Clean(f.outputDir)), upstreamDirName)
+}
+
+// snapshotDir 返回条目的快照目录: tmdb_upstream/{movie|tv}/{id}
+func (f *TMDBFetcher) snapshotDir(mediaType, mediaID string) string {
+	return filepath.Join(f.upstreamRoot(), mediaType, mediaID)
+}
+
+// saveSnapshot 保存TMDB返回的原始数据，整体替换该条目已有的快照
+func (f *TMDBFetcher) saveSnapshot(mediaType, mediaID string, files mediaFiles) (err error) {
+	stagingDir, err := newStagingDir(f.upstreamRoot(), mediaType, mediaID)
+	if err != nil {
+		return err
+	}
+	defer discardStagingDir(stagingDir, &err)
+
+	if err := saveMediaFiles(files, stagingDir); err != nil {
+		return err
+	}
+
+	meta := snapshotMeta{
+		MediaType: mediaType,
+		TMDBID:    mediaID,
+		FetchedAt: time.Now().UTC().Format(time.RFC3339),
+		Language:  f.config.Language,
+	}
+	for relPath := range files {
+		meta.Files = append(meta.Files, sourceFor(mediaType, mediaID, relPath))
+	}
+	sort.Slice(meta.Files, func(i, j int) bool {
+		return meta.Files[i].File < meta.Files[j].File
+	})
+	if err := saveJSON(meta, filepath.Join(stagingDir, snapshotMetaFile)); err != nil {
+		return err
+	}
+
+	return replaceWithStagingDir(stagingDir, f.snapshotDir(mediaType, mediaID))
+}
+
+// loadSnapshotFile 读取快照中的单个文件，快照不存在时返回 nil
+func (f *TMDBFetcher) loadSnapshotFile(mediaType, mediaID, relPath string) (map[string]interface{}, error) {
+	data, err := loadJSON(filepath.Join(f.snapshotDir(mediaType, mediaID), filepath.FromSlash(relPath)))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// loadSnapshotMeta 读取快照元信息
+func (f *TMDBFetcher) loadSnapshotMeta(mediaType, mediaID string) (*snapshotMeta, error) {
+	data, err := os.ReadFile(filepath.Join(f.snapshotDir(mediaType, mediaID), snapshotMetaFile))
+	if err != nil {
+		return nil, err
+	}
+	var meta snapshotMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("解析快照元信息失败: %v", err)
+	}
+	return &meta, nil
+}
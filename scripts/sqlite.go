@@ -0,0 +1,529 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"tmdb-manager/models"
+
+	_ "modernc.org/sqlite"
+)
+
+// defaultSQLitePath export sqlite 默认的数据库文件，相对于 scripts/cli 目录
+const defaultSQLitePath = "../tmdb_config.db"
+
+// sqliteSchemaVersion 表结构有不兼容的修改时递增，写入 meta 表
+const sqliteSchemaVersion = "1"
+
+// sqliteSchema 数据库的表结构
+// media_type 为 movie 或 tv；season_number、episode_number 为 NULL 表示条目本身的数据
+const sqliteSchema = `
+CREATE TABLE meta (
+	key   TEXT PRIMARY KEY,
+	value TEXT NOT NULL
+);
+
+CREATE TABLE media (
+	media_type        TEXT    NOT NULL,
+	media_id          INTEGER NOT NULL,
+	title             TEXT    NOT NULL,
+	original_title    TEXT,
+	original_language TEXT,
+	overview          TEXT,
+	tagline           TEXT,
+	release_date      TEXT,
+	status            TEXT,
+	runtime           INTEGER,
+	vote_average      REAL,
+	vote_count        INTEGER,
+	overlay           INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (media_type, media_id)
+);
+CREATE INDEX media_title ON media (title);
+CREATE INDEX media_original_title ON media (original_title);
+
+CREATE TABLE genres (
+	media_type TEXT    NOT NULL,
+	media_id   INTEGER NOT NULL,
+	genre_id   INTEGER NOT NULL,
+	name       TEXT    NOT NULL,
+	PRIMARY KEY (media_type, media_id, genre_id)
+);
+
+CREATE TABLE translations (
+	media_type    TEXT    NOT NULL,
+	media_id      INTEGER NOT NULL,
+	iso_639_1     TEXT    NOT NULL,
+	iso_3166_1    TEXT    NOT NULL,
+	language_name TEXT,
+	title         TEXT,
+	overview      TEXT,
+	tagline       TEXT,
+	PRIMARY KEY (media_type, media_id, iso_639_1, iso_3166_1)
+);
+CREATE INDEX translations_title ON translations (title);
+
+CREATE TABLE alternative_titles (
+	media_type TEXT    NOT NULL,
+	media_id   INTEGER NOT NULL,
+	iso_3166_1 TEXT    NOT NULL,
+	title      TEXT    NOT NULL,
+	type       TEXT
+);
+CREATE INDEX alternative_titles_media ON alternative_titles (media_type, media_id);
+CREATE INDEX alternative_titles_title ON alternative_titles (title);
+
+CREATE TABLE external_ids (
+	media_type TEXT    NOT NULL,
+	media_id   INTEGER NOT NULL,
+	source     TEXT    NOT NULL,
+	value      TEXT    NOT NULL,
+	PRIMARY KEY (media_type, media_id, source)
+);
+CREATE INDEX external_ids_value ON external_ids (source, value);
+
+CREATE TABLE release_dates (
+	movie_id      INTEGER NOT NULL,
+	iso_3166_1    TEXT    NOT NULL,
+	certification TEXT,
+	iso_639_1     TEXT,
+	note          TEXT,
+	release_date  TEXT,
+	type          INTEGER
+);
+CREATE INDEX release_dates_movie ON release_dates (movie_id, iso_3166_1);
+
+CREATE TABLE content_ratings (
+	tv_id      INTEGER NOT NULL,
+	iso_3166_1 TEXT    NOT NULL,
+	rating     TEXT,
+	PRIMARY KEY (tv_id, iso_3166_1)
+);
+
+CREATE TABLE seasons (
+	tv_id         INTEGER NOT NULL,
+	season_number INTEGER NOT NULL,
+	season_id     INTEGER,
+	name          TEXT,
+	overview      TEXT,
+	air_date      TEXT,
+	episode_count INTEGER,
+	PRIMARY KEY (tv_id, season_number)
+);
+
+CREATE TABLE episodes (
+	tv_id          INTEGER NOT NULL,
+	season_number  INTEGER NOT NULL,
+	episode_number INTEGER NOT NULL,
+	episode_id     INTEGER,
+	name           TEXT,
+	overview       TEXT,
+	air_date       TEXT,
+	runtime        INTEGER,
+	vote_average   REAL,
+	vote_count     INTEGER,
+	PRIMARY KEY (tv_id, season_number, episode_number)
+);
+
+CREATE TABLE people (
+	person_id            INTEGER PRIMARY KEY,
+	name                 TEXT NOT NULL,
+	original_name        TEXT,
+	gender               INTEGER,
+	known_for_department TEXT,
+	profile_path         TEXT
+);
+CREATE INDEX people_name ON people (name);
+
+CREATE TABLE credits (
+	media_type     TEXT    NOT NULL,
+	media_id       INTEGER NOT NULL,
+	season_number  INTEGER,
+	episode_number INTEGER,
+	person_id      INTEGER NOT NULL REFERENCES people (person_id),
+	kind           TEXT    NOT NULL, -- cast、crew 或 guest_star
+	character      TEXT,
+	department     TEXT,
+	job            TEXT,
+	episode_count  INTEGER,          -- 只用于电视剧全部季的汇总演职员
+	credit_order   INTEGER
+);
+CREATE INDEX credits_media ON credits (media_type, media_id);
+CREATE INDEX credits_person ON credits (person_id);
+`
+
+// sqliteExporter 把 tmdb_config 中维护的数据写入SQLite数据库
+type sqliteExporter struct {
+	local *TMDBFetcher // 只用于读取本地数据，不需要API Key
+	tx    *sql.Tx
+}
+
+// exportSQLite 把 tmdb_config 中的全部条目导出为SQLite数据库
+// 先写入同目录的临时文件，全部成功后再替换 dbPath，导出失败时原有的数据库不受影响
+func exportSQLite(outputDir, dbPath string) (counts map[string]int, err error) {
+	keys, err := listMediaKeys(outputDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		return nil, fmt.Errorf("创建目录失败: %v", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dbPath), "."+filepath.Base(dbPath)+".tmp-*")
+	if err != nil {
+		return nil, fmt.Errorf("创建临时文件失败: %v", err)
+	}
+	tmpPath := tmp.Name()
+	tmp.Close()
+	defer os.Remove(tmpPath) // 重命名成功后为空操作
+
+	db, err := sql.Open("sqlite", tmpPath)
+	if err != nil {
+		return nil, fmt.Errorf("打开数据库失败: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		return nil, fmt.Errorf("创建表失败: %v", err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+	e := &sqliteExporter{local: &TMDBFetcher{outputDir: outputDir}, tx: tx}
+
+	counts = map[string]int{"movie": 0, "tv": 0}
+	for _, key := range keys {
+		if err := e.exportMedia(key); err != nil {
+			return nil, fmt.Errorf("%s: %v", key, err)
+		}
+		counts[key.mediaType]++
+	}
+	if err := e.exec(`INSERT INTO meta (key, value) VALUES ('schema_version', ?), ('generated_at', ?)`,
+		sqliteSchemaVersion, time.Now().In(releaseTimeZone).Format(time.RFC3339)); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("写入数据库失败: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		return nil, fmt.Errorf("写入数据库失败: %v", err)
+	}
+	if err := os.Chmod(tmpPath, 0644); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmpPath, dbPath); err != nil {
+		return nil, fmt.Errorf("保存 %s 失败: %v", dbPath, err)
+	}
+	return counts, nil
+}
+
+// exec 执行一条SQL语句
+func (e *sqliteExporter) exec(query string, args ...interface{}) error {
+	if _, err := e.tx.Exec(query, args...); err != nil {
+		return fmt.Errorf("写入数据库失败: %v", err)
+	}
+	return nil
+}
+
+// loadModel 读取维护的数据文件并转换为结构体，文件不存在时返回 false
+func (e *sqliteExporter) loadModel(key mediaKey, relPath string, v interface{}) (bool, error) {
+	data, err := e.local.loadCuratedFile(key.mediaType, key.mediaID, relPath)
+	if err != nil || data == nil {
+		return false, err
+	}
+	if err := models.FromMap(data, v); err != nil {
+		return false, fmt.Errorf("%s: %v", relPath, err)
+	}
+	return true, nil
+}
+
+// exportMedia 写入一个条目的全部数据
+func (e *sqliteExporter) exportMedia(key mediaKey) error {
+	if key.mediaType == "movie" {
+		return e.exportMovie(key)
+	}
+	return e.exportTV(key)
+}
+
+// exportMovie 写入电影的详情、发行日期及附加数据
+func (e *sqliteExporter) exportMovie(key mediaKey) error {
+	var movie models.MovieDetails
+	if ok, err := e.loadModel(key, "details.json", &movie); err != nil || !ok {
+		return missingDetails(key, err)
+	}
+	if err := e.exec(`INSERT INTO media VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		key.mediaType, movie.ID, movie.DisplayTitle(), movie.OriginalTitle, movie.OriginalLanguage, movie.Overview,
+		movie.Tagline, nullString(string(movie.ReleaseDate)), movie.Status, movie.Runtime, movie.VoteAverage,
+		movie.VoteCount, e.local.hasOverlay(key.mediaType, key.mediaID)); err != nil {
+		return err
+	}
+	if err := e.exportCommon(key, movie.ID, movie.Genres, movie.Translations, movie.AlternativeTitles, movie.ExternalIDs); err != nil {
+		return err
+	}
+	if movie.IMDbID != nil && *movie.IMDbID != "" {
+		if err := e.exec(`INSERT OR IGNORE INTO external_ids VALUES (?, ?, 'imdb', ?)`, key.mediaType, movie.ID, *movie.IMDbID); err != nil {
+			return err
+		}
+	}
+	if err := e.exportCredits(key, movie.ID, nil, nil, movie.Credits); err != nil {
+		return err
+	}
+
+	var releaseDates models.ReleaseDates
+	if _, err := e.loadModel(key, "release_dates.json", &releaseDates); err != nil {
+		return err
+	}
+	for _, result := range releaseDates.Results {
+		for _, release := range result.ReleaseDates {
+			if err := e.exec(`INSERT INTO release_dates VALUES (?, ?, ?, ?, ?, ?, ?)`,
+				movie.ID, result.ISO3166_1, release.Certification, release.ISO639_1, release.Note,
+				nullString(string(release.ReleaseDate)), release.Type); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// exportTV 写入电视剧的详情、内容分级、附加数据以及已保存的季和集
+func (e *sqliteExporter) exportTV(key mediaKey) error {
+	var tv models.TVDetails
+	if ok, err := e.loadModel(key, "details.json", &tv); err != nil || !ok {
+		return missingDetails(key, err)
+	}
+	var runtime interface{}
+	if len(tv.EpisodeRunTime) > 0 {
+		runtime = tv.EpisodeRunTime[0]
+	}
+	if err := e.exec(`INSERT INTO media VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		key.mediaType, tv.ID, tv.DisplayTitle(), tv.OriginalName, tv.OriginalLanguage, tv.Overview,
+		tv.Tagline, nullString(string(tv.FirstAirDate)), tv.Status, runtime, tv.VoteAverage,
+		tv.VoteCount, e.local.hasOverlay(key.mediaType, key.mediaID)); err != nil {
+		return err
+	}
+	if err := e.exportCommon(key, tv.ID, tv.Genres, tv.Translations, tv.AlternativeTitles, tv.ExternalIDs); err != nil {
+		return err
+	}
+	if tv.AggregateCredits != nil {
+		if err := e.exportAggregateCredits(key, tv.ID, tv.AggregateCredits); err != nil {
+			return err
+		}
+	} else if err := e.exportCredits(key, tv.ID, nil, nil, tv.Credits); err != nil {
+		return err
+	}
+
+	var ratings models.ContentRatings
+	if _, err := e.loadModel(key, "content_ratings.json", &ratings); err != nil {
+		return err
+	}
+	for _, rating := range ratings.Results {
+		if err := e.exec(`INSERT OR REPLACE INTO content_ratings VALUES (?, ?, ?)`, tv.ID, rating.ISO3166_1, rating.Rating); err != nil {
+			return err
+		}
+	}
+
+	for _, summary := range tv.Seasons {
+		if err := e.exportSeason(key, tv.ID, summary); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportSeason 写入一季，已保存单季数据时同时写入每一集及其演职员
+func (e *sqliteExporter) exportSeason(key mediaKey, tvID int, summary models.SeasonSummary) error {
+	seasonPath := path.Join("season", strconv.Itoa(summary.SeasonNumber))
+	var season models.Season
+	ok, err := e.loadModel(key, path.Join(seasonPath, "details.json"), &season)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		// 没有单季数据时使用详情中的季概要
+		return e.exec(`INSERT INTO seasons VALUES (?, ?, ?, ?, ?, ?, ?)`, tvID, summary.SeasonNumber, summary.ID,
+			summary.Name, summary.Overview, nullString(string(summary.AirDate)), summary.EpisodeCount)
+	}
+
+	if err := e.exec(`INSERT INTO seasons VALUES (?, ?, ?, ?, ?, ?, ?)`, tvID, season.SeasonNumber, season.ID,
+		season.Name, season.Overview, nullString(string(season.AirDate)), len(season.Episodes)); err != nil {
+		return err
+	}
+	seasonNumber := season.SeasonNumber
+	if err := e.exportCredits(key, tvID, &seasonNumber, nil, season.Credits); err != nil {
+		return err
+	}
+
+	for _, episode := range season.Episodes {
+		// 单独保存的单集详情比季详情中的集列表更完整
+		var detailed models.Episode
+		episodePath := path.Join(seasonPath, "episode", strconv.Itoa(episode.EpisodeNumber), "details.json")
+		if ok, err := e.loadModel(key, episodePath, &detailed); err != nil {
+			return err
+		} else if ok {
+			episode = detailed
+		}
+
+		var runtime interface{}
+		if episode.Runtime != nil {
+			runtime = *episode.Runtime
+		}
+		if err := e.exec(`INSERT INTO episodes VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, tvID, seasonNumber,
+			episode.EpisodeNumber, episode.ID, episode.Name, episode.Overview, nullString(string(episode.AirDate)),
+			runtime, episode.VoteAverage, episode.VoteCount); err != nil {
+			return err
+		}
+		episodeNumber := episode.EpisodeNumber
+		credits := &models.Credits{Crew: episode.Crew}
+		if episode.Credits != nil {
+			credits.Cast = episode.Credits.Cast
+		}
+		if err := e.exportCredits(key, tvID, &seasonNumber, &episodeNumber, credits); err != nil {
+			return err
+		}
+		for i, guest := range episode.GuestStars {
+			if err := e.exportPerson(guest.ID, guest.Name, guest.OriginalName, guest.Gender, guest.KnownForDepartment, guest.ProfilePath); err != nil {
+				return err
+			}
+			if err := e.exec(`INSERT INTO credits VALUES (?, ?, ?, ?, ?, 'guest_star', ?, NULL, NULL, NULL, ?)`,
+				key.mediaType, tvID, seasonNumber, episodeNumber, guest.ID, guest.Character, i); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// exportCommon 写入电影和电视剧共有的类型、翻译、别名和外部ID
+func (e *sqliteExporter) exportCommon(key mediaKey, mediaID int, genres []models.Genre, translations *models.Translations,
+	alternatives *models.AlternativeTitles, external *models.ExternalIDs) error {
+	for _, genre := range genres {
+		if err := e.exec(`INSERT OR IGNORE INTO genres VALUES (?, ?, ?, ?)`, key.mediaType, mediaID, genre.ID, genre.Name); err != nil {
+			return err
+		}
+	}
+
+	if translations != nil {
+		for _, t := range translations.Translations {
+			title := t.Data.Title
+			if title == "" {
+				title = t.Data.Name
+			}
+			if err := e.exec(`INSERT OR REPLACE INTO translations VALUES (?, ?, ?, ?, ?, ?, ?, ?)`, key.mediaType, mediaID,
+				t.ISO639_1, t.ISO3166_1, t.Name, nullString(title), nullString(t.Data.Overview), nullString(t.Data.Tagline)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if alternatives != nil {
+		for _, alt := range alternatives.List() {
+			if err := e.exec(`INSERT INTO alternative_titles VALUES (?, ?, ?, ?, ?)`, key.mediaType, mediaID,
+				alt.ISO3166_1, alt.Title, nullString(alt.Type)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if external != nil {
+		ids := map[string]*string{
+			"imdb":      external.IMDbID,
+			"wikidata":  external.WikidataID,
+			"facebook":  external.FacebookID,
+			"instagram": external.InstagramID,
+			"twitter":   external.TwitterID,
+		}
+		if external.TVDBID != nil {
+			tvdb := strconv.Itoa(*external.TVDBID)
+			ids["tvdb"] = &tvdb
+		}
+		for source, value := range ids {
+			if value == nil || *value == "" {
+				continue
+			}
+			if err := e.exec(`INSERT INTO external_ids VALUES (?, ?, ?, ?)`, key.mediaType, mediaID, source, *value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// exportCredits 写入演职员，season、episode 为 nil 表示条目本身的演职员
+func (e *sqliteExporter) exportCredits(key mediaKey, mediaID int, season, episode *int, credits *models.Credits) error {
+	if credits == nil {
+		return nil
+	}
+	for i, c := range credits.Cast {
+		if err := e.exportPerson(c.ID, c.Name, c.OriginalName, c.Gender, c.KnownForDepartment, c.ProfilePath); err != nil {
+			return err
+		}
+		if err := e.exec(`INSERT INTO credits VALUES (?, ?, ?, ?, ?, 'cast', ?, NULL, NULL, NULL, ?)`,
+			key.mediaType, mediaID, season, episode, c.ID, c.Character, i); err != nil {
+			return err
+		}
+	}
+	for i, c := range credits.Crew {
+		if err := e.exportPerson(c.ID, c.Name, c.OriginalName, c.Gender, c.KnownForDepartment, c.ProfilePath); err != nil {
+			return err
+		}
+		if err := e.exec(`INSERT INTO credits VALUES (?, ?, ?, ?, ?, 'crew', NULL, ?, ?, NULL, ?)`,
+			key.mediaType, mediaID, season, episode, c.ID, c.Department, c.Job, i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportAggregateCredits 写入电视剧全部季的汇总演职员，每个角色或职位一行
+func (e *sqliteExporter) exportAggregateCredits(key mediaKey, tvID int, credits *models.AggregateCredits) error {
+	for i, c := range credits.Cast {
+		if err := e.exportPerson(c.ID, c.Name, c.OriginalName, c.Gender, c.KnownForDepartment, c.ProfilePath); err != nil {
+			return err
+		}
+		for _, role := range c.Roles {
+			if err := e.exec(`INSERT INTO credits VALUES (?, ?, NULL, NULL, ?, 'cast', ?, NULL, NULL, ?, ?)`,
+				key.mediaType, tvID, c.ID, role.Character, role.EpisodeCount, i); err != nil {
+				return err
+			}
+		}
+	}
+	for i, c := range credits.Crew {
+		if err := e.exportPerson(c.ID, c.Name, c.OriginalName, c.Gender, c.KnownForDepartment, c.ProfilePath); err != nil {
+			return err
+		}
+		for _, job := range c.Jobs {
+			if err := e.exec(`INSERT INTO credits VALUES (?, ?, NULL, NULL, ?, 'crew', NULL, ?, ?, ?, ?)`,
+				key.mediaType, tvID, c.ID, c.Department, job.Job, job.EpisodeCount, i); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// exportPerson 写入人物，同一人物在多个条目中出现时只保留第一次的信息
+func (e *sqliteExporter) exportPerson(id int, name, originalName string, gender int, department string, profilePath *string) error {
+	var profile interface{}
+	if profilePath != nil {
+		profile = *profilePath
+	}
+	return e.exec(`INSERT OR IGNORE INTO people VALUES (?, ?, ?, ?, ?, ?)`, id, name, nullString(originalName), gender, nullString(department), profile)
+}
+
+// nullString 空字符串写入为 NULL
+func nullString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
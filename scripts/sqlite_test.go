@@ -0,0 +1,63 @@
+package main
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func TestExportSQLite(t *testing.T) {
+	root := t.TempDir()
+	configDir := filepath.Join(root, "tmdb_config")
+	writeFiles(t, configDir, testExportTree)
+	dbPath := filepath.Join(root, "tmdb_config.db")
+
+	counts, err := exportSQLite(configDir, dbPath)
+	if err != nil {
+		t.Fatalf("exportSQLite() 失败: %v", err)
+	}
+	if counts["movie"] != 1 || counts["tv"] != 1 {
+		t.Errorf("导出的条目数 = %v, 期望电影和电视剧各 1 个", counts)
+	}
+
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for table, want := range map[string]int{
+		"media":           2,
+		"genres":          3,
+		"credits":         10, // 电影 2 个演员 3 个职位，电视剧 1 个演员 2 个角色，单集 1 个演员 1 个职位 1 个客串
+		"people":          7,
+		"external_ids":    4, // details.json 中的 imdb_id 与 external_ids 重复，只写入一次
+		"release_dates":   4,
+		"content_ratings": 2,
+		"seasons":         1,
+		"episodes":        1,
+	} {
+		var got int
+		if err := db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&got); err != nil {
+			t.Fatalf("查询 %s 失败: %v", table, err)
+		}
+		if got != want {
+			t.Errorf("%s 有 %d 行, 期望 %d 行", table, got, want)
+		}
+	}
+
+	var mediaType string
+	var mediaID int
+	if err := db.QueryRow(`SELECT media_type, media_id FROM external_ids WHERE source = 'tvdb' AND value = '420531'`).Scan(&mediaType, &mediaID); err != nil || mediaType != "tv" || mediaID != 95480 {
+		t.Errorf("通过TVDB ID查找 = %s/%d, %v, 期望 tv/95480", mediaType, mediaID, err)
+	}
+	var title string
+	var runtime int
+	if err := db.QueryRow(`SELECT title, runtime FROM media WHERE media_type = 'movie' AND media_id = 842675`).Scan(&title, &runtime); err != nil || title != "流浪地球2" || runtime != 173 {
+		t.Errorf("电影 = %s %d, %v, 期望 流浪地球2 173", title, runtime, err)
+	}
+	var certification string
+	if err := db.QueryRow(`SELECT certification FROM release_dates WHERE movie_id = 842675 AND iso_3166_1 = 'US' AND type = 3`).Scan(&certification); err != nil || certification != "PG-13" {
+		t.Errorf("US 院线分级 = %q, %v, 期望 PG-13", certification, err)
+	}
+}
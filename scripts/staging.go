@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// stagingDirName 暂存目录名，位于数据根目录 (tmdb_config 等) 下，已在 .gitignore 中忽略
+const stagingDirName = ".staging"
+
+// newStagingDir 在数据根目录下为一个条目创建独立的暂存目录
+// 暂存目录与目标目录位于同一文件系统，保证最终的重命名是原子操作
+func newStagingDir(rootDir, mediaType, mediaID string) (string, error) {
+	root := filepath.Join(rootDir, stagingDirName)
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return "", fmt.Errorf("创建暂存目录失败: %v", err)
+	}
+	dir, err := os.MkdirTemp(root, mediaType+"-"+mediaID+"-*")
+	if err != nil {
+		return "", fmt.Errorf("创建暂存目录失败: %v", err)
+	}
+	return dir, nil
+}
+
+// commitStagingDir 将完整写入的暂存目录整体移动到目标目录
+func commitStagingDir(stagingDir, targetDir string) error {
+	if err := os.MkdirAll(filepath.Dir(targetDir), 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %v", err)
+	}
+	if err := os.Chmod(stagingDir, 0755); err != nil {
+		return fmt.Errorf("设置目录权限失败: %v", err)
+	}
+	if err := os.Rename(stagingDir, targetDir); err != nil {
+		return fmt.Errorf("移动数据到 %s 失败: %v", targetDir, err)
+	}
+	return nil
+}
+
+// replaceWithStagingDir 用暂存目录替换已存在的目标目录
+// 先把旧目录重命名到暂存目录旁，新目录移入成功后才删除旧目录，移入失败时恢复旧目录
+func replaceWithStagingDir(stagingDir, targetDir string) error {
+	backupDir := stagingDir + ".old"
+	if err := os.Rename(targetDir, backupDir); os.IsNotExist(err) {
+		return commitStagingDir(stagingDir, targetDir)
+	} else if err != nil {
+		return fmt.Errorf("移走旧目录 %s 失败: %v", targetDir, err)
+	}
+	if err := commitStagingDir(stagingDir, targetDir); err != nil {
+		if restoreErr := os.Rename(backupDir, targetDir); restoreErr != nil {
+			return fmt.Errorf("%v；恢复旧目录失败，旧数据保存在 %s: %v", err, backupDir, restoreErr)
+		}
+		return err
+	}
+	os.RemoveAll(backupDir)
+	return nil
+}
+
+// discardStagingDir 清理暂存目录，配合 defer 使用
+// 成功提交后暂存目录已被移走，这里只会清理失败时留下的内容
+// 暂存根目录保留不删除 (已在 .gitignore 中忽略)，避免并发的批量获取在其中创建暂存目录时失败
+func discardStagingDir(stagingDir string, errp *error) {
+	if _, err := os.Stat(stagingDir); err != nil {
+		return
+	}
+	os.RemoveAll(stagingDir)
+	if *errp != nil {
+		fmt.Println("已丢弃未完成的数据，目标目录未做任何改动")
+	}
+}
@@ -3,16 +3,21 @@ package main
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 	"time"
+
+	"tmdb-manager/models"
 )
 
 // Config 配置文件结构
@@ -23,6 +28,10 @@ type Config struct {
 		Enabled bool   `json:"enabled"`
 		URL     string `json:"url"`
 	} `json:"proxy"`
+	RequestsPerSecond float64 `json:"requests_per_second"` // 每秒最多请求数，<=0 时使用默认值
+	Workers           int     `json:"workers"`             // 批量获取时的并发数
+	Timeout           int     `json:"timeout"`             // 单次请求超时（秒）
+	MaxRetries        int     `json:"max_retries"`         // 临时错误的最大重试次数，0 表示不重试，未设置时使用默认值
 }
 
 // TMDBFetcher TMDB数据获取器
@@ -30,8 +39,25 @@ type TMDBFetcher struct {
 	config     Config
 	httpClient *http.Client
 	baseURL    string
+	outputDir  string // 元数据保存目录 (tmdb_config)
+	limiter    *rateLimiter
 }
 
+// 请求相关的默认值
+const (
+	defaultRequestsPerSecond = 20
+	defaultWorkers           = 4
+	defaultTimeout           = 30
+	defaultMaxRetries        = 3
+)
+
+// 默认路径均相对于 scripts/cli 目录
+const (
+	defaultConfigPath = "../cli/config.json"
+	defaultOutputDir  = "../tmdb_config"
+	defaultRepoDir    = ".."
+)
+
 const banner = `============================================================
   TMDB 数据管理工具
   获取TMDB API数据 / 管理本地元数据 / 提交PR
@@ -39,7 +65,7 @@ const banner = `============================================================
 `
 
 // NewTMDBFetcher 创建新的TMDB获取器
-func NewTMDBFetcher(configPath string) (*TMDBFetcher, error) {
+func NewTMDBFetcher(configPath, outputDir string) (*TMDBFetcher, error) {
 	config, err := loadConfig(configPath)
 	if err != nil {
 		return nil, err
@@ -54,9 +80,25 @@ func NewTMDBFetcher(configPath string) (*TMDBFetcher, error) {
 		config.Language = "zh-CN"
 	}
 
+	// 设置默认请求速率、并发数、超时和重试次数
+	if config.RequestsPerSecond <= 0 {
+		config.RequestsPerSecond = defaultRequestsPerSecond
+	}
+	if config.Workers <= 0 {
+		config.Workers = defaultWorkers
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultTimeout
+	}
+	if config.MaxRetries < 0 {
+		config.MaxRetries = defaultMaxRetries
+	}
+
 	fetcher := &TMDBFetcher{
-		config:  config,
-		baseURL: "https://api.themoviedb.org/3",
+		config:    config,
+		baseURL:   "https://api.themoviedb.org/3",
+		outputDir: outputDir,
+		limiter:   newRateLimiter(config.RequestsPerSecond),
 	}
 
 	// 创建HTTP客户端
@@ -67,7 +109,8 @@ func NewTMDBFetcher(configPath string) (*TMDBFetcher, error) {
 
 // loadConfig 加载配置文件
 func loadConfig(configPath string) (Config, error) {
-	var config Config
+	// max_retries 允许设置为 0，先设为 -1 以区分未设置的情况
+	config := Config{MaxRetries: -1}
 
 	file, err := os.Open(configPath)
 	if err != nil {
@@ -101,7 +144,7 @@ func createHTTPClient(config Config) *http.Client {
 
 	return &http.Client{
 		Transport: transport,
-		Timeout:   30 * time.Second,
+		Timeout:   time.Duration(config.Timeout) * time.Second,
 	}
 }
 
@@ -124,33 +167,75 @@ func (f *TMDBFetcher) makeRequest(endpoint string, params map[string]string) (ma
 		reqURL += "?" + values.Encode()
 	}
 
-	fmt.Printf("正在请求: %s\n", endpoint)
+	for attempt := 1; ; attempt++ {
+		// 等待速率限制器放行
+		f.limiter.wait()
+
+		fmt.Printf("正在请求: %s\n", endpoint)
+
+		result, err := f.doRequest(reqURL, endpoint)
+		if err == nil {
+			return result, nil
+		}
+		if attempt > f.config.MaxRetries || !isRetryable(err) {
+			return nil, err
+		}
+
+		// 临时错误按指数退避重试，429 优先使用 Retry-After
+		wait := retryBackoff(attempt)
+		var apiErr *apiError
+		if errors.As(err, &apiErr) && apiErr.retryAfter > 0 {
+			wait = apiErr.retryAfter
+		}
+		fmt.Printf("%v，%v 后重试 (%d/%d): %s\n", err, wait.Round(100*time.Millisecond), attempt, f.config.MaxRetries, endpoint)
+
+		if errors.Is(err, errRateLimited) {
+			// 触发TMDB限流时让所有并发请求一起暂停
+			f.limiter.pause(wait)
+		} else {
+			time.Sleep(wait)
+		}
+	}
+}
 
+// doRequest 发送一次GET请求并解析JSON响应
+func (f *TMDBFetcher) doRequest(reqURL, endpoint string) (map[string]interface{}, error) {
 	resp, err := f.httpClient.Get(reqURL)
 	if err != nil {
-		return nil, fmt.Errorf("请求失败: %v", err)
+		return nil, &requestError{op: "请求失败", err: err}
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API返回错误 %d: %s", resp.StatusCode, string(body))
+		apiErr := &apiError{statusCode: resp.StatusCode, endpoint: endpoint, body: string(body)}
+		if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+			apiErr.retryAfter = parseRetryAfter(retryAfter)
+		}
+		return nil, apiErr
 	}
 
 	var result map[string]interface{}
 	decoder := json.NewDecoder(resp.Body)
 	if err := decoder.Decode(&result); err != nil {
-		return nil, fmt.Errorf("解析响应失败: %v", err)
+		return nil, &requestError{op: "解析响应失败", err: err}
 	}
 
 	return result, nil
 }
 
+// 详情请求中附加的数据
+const (
+	movieAppendToResponse   = "credits,alternative_titles,translations,external_ids"
+	tvAppendToResponse      = "credits,alternative_titles,translations,external_ids,aggregate_credits"
+	episodeAppendToResponse = "credits,translations,external_ids" // 季和集共用
+)
+
 // fetchMovieDetails 获取电影详细信息
 func (f *TMDBFetcher) fetchMovieDetails(movieID string) (map[string]interface{}, error) {
 	endpoint := fmt.Sprintf("/movie/%s", movieID)
 	params := map[string]string{
-		"append_to_response": "credits,alternative_titles,translations,external_ids",
+		"append_to_response": movieAppendToResponse,
 	}
 	return f.makeRequest(endpoint, params)
 }
@@ -165,7 +250,7 @@ func (f *TMDBFetcher) fetchMovieReleaseDates(movieID string) (map[string]interfa
 func (f *TMDBFetcher) fetchTVDetails(tvID string) (map[string]interface{}, error) {
 	endpoint := fmt.Sprintf("/tv/%s", tvID)
 	params := map[string]string{
-		"append_to_response": "credits,alternative_titles,translations,external_ids,aggregate_credits",
+		"append_to_response": tvAppendToResponse,
 	}
 	return f.makeRequest(endpoint, params)
 }
@@ -176,31 +261,87 @@ func (f *TMDBFetcher) fetchTVContentRatings(tvID string) (map[string]interface{}
 	return f.makeRequest(endpoint, nil)
 }
 
+// fetchTVSeason 获取电视剧单季信息
+func (f *TMDBFetcher) fetchTVSeason(tvID string, seasonNumber int) (map[string]interface{}, error) {
+	endpoint := fmt.Sprintf("/tv/%s/season/%d", tvID, seasonNumber)
+	params := map[string]string{
+		"append_to_response": episodeAppendToResponse,
+	}
+	return f.makeRequest(endpoint, params)
+}
+
+// fetchTVEpisode 获取电视剧单集信息
+func (f *TMDBFetcher) fetchTVEpisode(tvID string, seasonNumber, episodeNumber int) (map[string]interface{}, error) {
+	endpoint := fmt.Sprintf("/tv/%s/season/%d/episode/%d", tvID, seasonNumber, episodeNumber)
+	params := map[string]string{
+		"append_to_response": episodeAppendToResponse,
+	}
+	return f.makeRequest(endpoint, params)
+}
+
 // saveJSON 保存JSON数据到文件
-func saveJSON(data map[string]interface{}, filePath string) error {
-	// 确保目录存在
+// 通过 writeFileAtomic 写入，中途中断不会留下被截断的文件
+func saveJSON(data interface{}, filePath string) error {
+	encoded, err := encodeJSONFile(data)
+	if err != nil {
+		return fmt.Errorf("写入文件失败: %v", err)
+	}
+	if err := writeFileAtomic(filePath, encoded); err != nil {
+		return fmt.Errorf("保存文件失败: %v", err)
+	}
+
+	fmt.Printf("已保存: %s\n", filePath)
+	return nil
+}
+
+// writeFileAtomic 先写入同目录下的临时文件并同步到磁盘再重命名
+// 中途中断不会留下被截断的文件，并发读取时也不会读到写了一半的文件
+func writeFileAtomic(filePath string, data []byte) error {
 	dir := filepath.Dir(filePath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("创建目录失败: %v", err)
+		return err
 	}
-
-	// 保存JSON文件
-	file, err := os.Create(filePath)
+	file, err := os.CreateTemp(dir, "."+filepath.Base(filePath)+".tmp-*")
 	if err != nil {
-		return fmt.Errorf("创建文件失败: %v", err)
+		return err
 	}
-	defer file.Close()
+	tmpPath := file.Name()
+	defer os.Remove(tmpPath) // 重命名成功后为空操作
 
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
-	encoder.SetEscapeHTML(false)
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, filePath)
+}
 
-	if err := encoder.Encode(data); err != nil {
-		return fmt.Errorf("写入文件失败: %v", err)
+// loadJSON 读取JSON文件
+func loadJSON(filePath string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
 	}
 
-	fmt.Printf("已保存: %s\n", filePath)
-	return nil
+	var result map[string]interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %v", filePath, err)
+	}
+	return result, nil
+}
+
+// mediaDir 返回媒体数据的保存目录: {outputDir}/{movie|tv}/{id}
+func (f *TMDBFetcher) mediaDir(mediaType, mediaID string) string {
+	return filepath.Join(f.outputDir, mediaType, mediaID)
 }
 
 // checkDirectoryExists 检查目录是否存在
@@ -209,92 +350,197 @@ func checkDirectoryExists(dir string) bool {
 	return err == nil && info.IsDir()
 }
 
-// fetchAndSaveMovie 获取并保存电影数据
-func (f *TMDBFetcher) fetchAndSaveMovie(movieID string) error {
-	fmt.Printf("\n开始获取电影 ID: %s 的数据...\n", movieID)
+// mediaFiles 一个条目的全部数据文件
+// key 为相对于条目目录的路径，如 "details.json"、"season/1/details.json"
+type mediaFiles map[string]map[string]interface{}
 
-	// 创建目录 (保存到上级目录的tmdb_config文件夹)
-	baseDir := filepath.Join("..", "tmdb_config", "movie", movieID)
+// mediaLabel 返回媒体类型的中文名称
+func mediaLabel(mediaType string) string {
+	if mediaType == "movie" {
+		return "电影"
+	}
+	return "电视剧"
+}
 
-	// 检查目录是否已存在
-	if checkDirectoryExists(baseDir) {
-		absPath, _ := filepath.Abs(baseDir)
-		fmt.Printf("\n⚠️  警告: 目录已存在: %s\n", baseDir)
-		fmt.Println("该电影数据已经生成，为防止覆盖已维护的元数据，操作已取消。")
-		fmt.Println("\n如需重新生成，请先手动删除该目录:")
-		fmt.Printf("  rmdir /s \"%s\"\n", absPath)
-		return nil
+// mediaTitle 从详情中取出标题，没有中文标题时使用原始标题
+func mediaTitle(mediaType string, details map[string]interface{}) string {
+	if mediaType == "movie" {
+		var movie models.MovieDetails
+		if err := models.FromMap(details, &movie); err != nil {
+			return ""
+		}
+		return movie.DisplayTitle()
+	}
+	var tv models.TVDetails
+	if err := models.FromMap(details, &tv); err != nil {
+		return ""
+	}
+	return tv.DisplayTitle()
+}
+
+// fetchMediaFiles 按媒体类型获取条目的全部数据文件
+func (f *TMDBFetcher) fetchMediaFiles(mediaType, mediaID string) (mediaFiles, error) {
+	if mediaType == "movie" {
+		return f.fetchMovieFiles(mediaID)
 	}
+	return f.fetchTVFiles(mediaID)
+}
+
+// fetchMovieFiles 获取电影的详细信息和发行日期
+func (f *TMDBFetcher) fetchMovieFiles(movieID string) (mediaFiles, error) {
+	files := make(mediaFiles)
 
-	// 获取并保存详细信息
+	// 获取详细信息
 	details, err := f.fetchMovieDetails(movieID)
 	if err != nil {
-		return err
-	}
-	if err := saveJSON(details, filepath.Join(baseDir, "details.json")); err != nil {
-		return err
+		return nil, err
 	}
+	files["details.json"] = details
 
-	// 获取并保存发行日期
+	// 获取发行日期
 	releaseDates, err := f.fetchMovieReleaseDates(movieID)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if err := saveJSON(releaseDates, filepath.Join(baseDir, "release_dates.json")); err != nil {
-		return err
+	files["release_dates.json"] = releaseDates
+
+	return files, nil
+}
+
+// fetchTVFiles 获取电视剧的详细信息、内容分级以及各季各集信息
+func (f *TMDBFetcher) fetchTVFiles(tvID string) (mediaFiles, error) {
+	files := make(mediaFiles)
+
+	// 获取详细信息
+	details, err := f.fetchTVDetails(tvID)
+	if err != nil {
+		return nil, err
 	}
+	files["details.json"] = details
 
-	fmt.Println("\n✓ 电影数据获取完成!")
-	if title, ok := details["title"].(string); ok {
-		fmt.Printf("  标题: %s\n", title)
-	} else if origTitle, ok := details["original_title"].(string); ok {
-		fmt.Printf("  标题: %s\n", origTitle)
+	// 获取内容分级
+	contentRatings, err := f.fetchTVContentRatings(tvID)
+	if err != nil {
+		return nil, err
 	}
-	fmt.Printf("  目录: %s\n", baseDir)
+	files["content_ratings.json"] = contentRatings
+
+	// 获取各季及各集信息
+	if err := f.fetchTVSeasonFiles(tvID, details, files); err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
 
+// fetchTVSeasonFiles 遍历详情中的seasons列表，获取每一季及其每一集的数据
+// 季数据保存到 season/{season_number}/details.json，
+// 集数据保存到 season/{season_number}/episode/{episode_number}/details.json
+func (f *TMDBFetcher) fetchTVSeasonFiles(tvID string, details map[string]interface{}, files mediaFiles) error {
+	seasons, _ := details["seasons"].([]interface{})
+	for _, s := range seasons {
+		season, ok := s.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		seasonNumber, ok := season["season_number"].(float64)
+		if !ok {
+			continue
+		}
+
+		seasonDetails, err := f.fetchTVSeason(tvID, int(seasonNumber))
+		if err != nil {
+			return err
+		}
+		seasonDir := path.Join("season", fmt.Sprintf("%d", int(seasonNumber)))
+		files[path.Join(seasonDir, "details.json")] = seasonDetails
+
+		episodes, _ := seasonDetails["episodes"].([]interface{})
+		for _, e := range episodes {
+			episode, ok := e.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			episodeNumber, ok := episode["episode_number"].(float64)
+			if !ok {
+				continue
+			}
+
+			episodeDetails, err := f.fetchTVEpisode(tvID, int(seasonNumber), int(episodeNumber))
+			if err != nil {
+				return err
+			}
+			episodeDir := path.Join(seasonDir, "episode", fmt.Sprintf("%d", int(episodeNumber)))
+			files[path.Join(episodeDir, "details.json")] = episodeDetails
+		}
+	}
+
+	return nil
+}
+
+// saveMediaFiles 将全部数据文件保存到目录
+func saveMediaFiles(files mediaFiles, dir string) error {
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		if err := saveJSON(files[p], filepath.Join(dir, filepath.FromSlash(p))); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-// fetchAndSaveTV 获取并保存电视剧数据
-func (f *TMDBFetcher) fetchAndSaveTV(tvID string) error {
-	fmt.Printf("\n开始获取电视剧 ID: %s 的数据...\n", tvID)
+// fetchAndSave 获取并保存电影/电视剧数据
+func (f *TMDBFetcher) fetchAndSave(mediaType, mediaID string) (err error) {
+	label := mediaLabel(mediaType)
+	fmt.Printf("\n开始获取%s ID: %s 的数据...\n", label, mediaID)
 
-	// 创建目录 (保存到上级目录的tmdb_config文件夹)
-	baseDir := filepath.Join("..", "tmdb_config", "tv", tvID)
+	// 创建目录 (默认保存到上级目录的tmdb_config文件夹)
+	baseDir := f.mediaDir(mediaType, mediaID)
 
 	// 检查目录是否已存在
 	if checkDirectoryExists(baseDir) {
-		absPath, _ := filepath.Abs(baseDir)
 		fmt.Printf("\n⚠️  警告: 目录已存在: %s\n", baseDir)
-		fmt.Println("该电视剧数据已经生成，为防止覆盖已维护的元数据，操作已取消。")
-		fmt.Println("\n如需重新生成，请先手动删除该目录:")
-		fmt.Printf("  rmdir /s \"%s\"\n", absPath)
+		fmt.Printf("该%s数据已经生成，为防止覆盖已维护的元数据，操作已取消。\n", label)
+		fmt.Println("\n如需更新TMDB数据并保留已维护的修改，请使用刷新功能:")
+		fmt.Printf("  tmdb-manager refresh %s %s\n", mediaType, mediaID)
 		return nil
 	}
 
-	// 获取并保存详细信息
-	details, err := f.fetchTVDetails(tvID)
+	files, err := f.fetchMediaFiles(mediaType, mediaID)
 	if err != nil {
 		return err
 	}
-	if err := saveJSON(details, filepath.Join(baseDir, "details.json")); err != nil {
+
+	// 保存前提示疑似被恶意编辑的标题和简介，由维护者核对后修正
+	printSuspicions(mediaType, mediaID, detectVandalism(mediaType, files))
+
+	// 所有文件先写入暂存目录，全部成功后再整体移动到目标目录
+	stagingDir, err := newStagingDir(f.outputDir, mediaType, mediaID)
+	if err != nil {
 		return err
 	}
+	defer discardStagingDir(stagingDir, &err)
 
-	// 获取并保存内容分级
-	contentRatings, err := f.fetchTVContentRatings(tvID)
-	if err != nil {
+	if err := saveMediaFiles(files, stagingDir); err != nil {
 		return err
 	}
-	if err := saveJSON(contentRatings, filepath.Join(baseDir, "content_ratings.json")); err != nil {
+	if err := commitStagingDir(stagingDir, baseDir); err != nil {
 		return err
 	}
 
-	fmt.Println("\n✓ 电视剧数据获取完成!")
-	if name, ok := details["name"].(string); ok {
-		fmt.Printf("  标题: %s\n", name)
-	} else if origName, ok := details["original_name"].(string); ok {
-		fmt.Printf("  标题: %s\n", origName)
+	// 保存TMDB原始数据快照，供以后刷新时三方合并使用
+	if err := f.saveSnapshot(mediaType, mediaID, files); err != nil {
+		fmt.Printf("⚠️  保存TMDB原始数据快照失败: %v\n", err)
+	}
+
+	fmt.Printf("\n✓ %s数据获取完成!\n", label)
+	if title := mediaTitle(mediaType, files["details.json"]); title != "" {
+		fmt.Printf("  标题: %s\n", title)
 	}
 	fmt.Printf("  目录: %s\n", baseDir)
 
@@ -302,62 +548,114 @@ func (f *TMDBFetcher) fetchAndSaveTV(tvID string) error {
 }
 
 // getMediaType 获取媒体类型
-func getMediaType(reader *bufio.Reader) (string, error) {
+// 用户直接粘贴链接或外部ID时，媒体类型为空，粘贴的内容作为第二个返回值交给调用方解析
+func getMediaType(reader *bufio.Reader) (string, string, error) {
 	for {
 		fmt.Println("\n请选择媒体类型:")
 		fmt.Println("  1. 电影 (Movie)")
 		fmt.Println("  2. 电视剧 (TV Show)")
 		fmt.Println("  q. 退出")
+		fmt.Println("  也可以直接粘贴TMDB/IMDb链接或IMDb ID (tt开头)")
 		fmt.Print("\n请输入选项 (1/2/q): ")
 
 		input, err := reader.ReadString('\n')
 		if err != nil {
-			return "", err
+			return "", "", err
 		}
 
 		input = strings.TrimSpace(input)
 
 		switch input {
 		case "1":
-			return "movie", nil
+			return "movie", "", nil
 		case "2":
-			return "tv", nil
+			return "tv", "", nil
 		case "q", "Q":
-			return "quit", nil
+			return "quit", "", nil
 		default:
+			if isMediaRef(input) {
+				return "", input, nil
+			}
 			fmt.Println("无效的选项，请重新输入")
 		}
 	}
 }
 
-// getMediaID 获取媒体ID
-func getMediaID(reader *bufio.Reader) (string, error) {
+// promptMedia 交互式获取要处理的条目，返回媒体类型和TMDB ID，用户退出时ID为 "quit"
+// 粘贴了链接时直接从链接确定条目，跳过输入ID的步骤
+func (f *TMDBFetcher) promptMedia(reader *bufio.Reader) (string, string, error) {
 	for {
-		fmt.Print("\n请输入TMDB ID (或输入 'q' 退出): ")
+		mediaType, ref, err := getMediaType(reader)
+		if err != nil {
+			return "", "", err
+		}
+		if mediaType == "quit" {
+			return "", "quit", nil
+		}
+		if ref == "" {
+			return f.getMediaID(reader, mediaType)
+		}
+
+		resolvedType, mediaID, err := f.resolveMediaID("", ref)
+		if err != nil {
+			fmt.Printf("\n无法识别: %v\n", err)
+			continue
+		}
+		return resolvedType, mediaID, nil
+	}
+}
+
+// getMediaID 获取媒体ID，返回的媒体类型可能因链接或外部ID的查找结果而与 mediaType 不同
+// 支持TMDB ID、TMDB/IMDb链接、外部ID (tt1234567、tvdb:12345 等)，其他输入按标题搜索并从候选列表中选择
+func (f *TMDBFetcher) getMediaID(reader *bufio.Reader, mediaType string) (string, string, error) {
+	for {
+		fmt.Print("\n请输入TMDB ID、链接、IMDb ID (tt开头) 或标题关键字 (或输入 'q' 退出): ")
 
 		input, err := reader.ReadString('\n')
 		if err != nil {
-			return "", err
+			return "", "", err
 		}
 
 		input = strings.TrimSpace(input)
 
 		if input == "q" || input == "Q" {
-			return "quit", nil
+			return mediaType, "quit", nil
 		}
 
 		if input == "" {
-			fmt.Println("ID不能为空，请重新输入")
+			fmt.Println("输入不能为空，请重新输入")
 			continue
 		}
 
-		return input, nil
+		if isNumericID(input) {
+			return mediaType, input, nil
+		}
+
+		if isMediaRef(input) {
+			resolvedType, mediaID, err := f.resolveMediaID(mediaType, input)
+			if err != nil {
+				fmt.Printf("\n查找失败: %v\n", err)
+				continue
+			}
+			return resolvedType, mediaID, nil
+		}
+
+		mediaID, err := f.chooseSearchResult(reader, mediaType, input)
+		if err != nil {
+			fmt.Printf("\n搜索失败: %v\n", err)
+			continue
+		}
+		if mediaID == "" {
+			continue
+		}
+		return mediaType, mediaID, nil
 	}
 }
+
 // openBrowser 在默认浏览器中打开URL
 func openBrowser(url string) error {
 	var cmd *exec.Cmd
-	
+
 	switch runtime.GOOS {
 	case "windows":
 		cmd = exec.Command("cmd", "/c", "start", url)
@@ -368,7 +666,7 @@ func openBrowser(url string) error {
 	default:
 		return fmt.Errorf("不支持的操作系统")
 	}
-	
+
 	return cmd.Start()
 }
 
@@ -384,40 +682,181 @@ func getContinue(reader *bufio.Reader) bool {
 	return input == "y" || input == "yes"
 }
 
-// submitPullRequest 提交PR到GitHub
-func submitPullRequest(reader *bufio.Reader) error {
-	fmt.Println("\n" + strings.Repeat("=", 60))
-	fmt.Println("📤 一键提交PR到 GitHub")
-	fmt.Println(strings.Repeat("=", 60))
-
+// resolveGitRepo 检查git是否可用，并返回项目根目录的绝对路径
+func resolveGitRepo(repoDir string) (string, error) {
 	// 检查git是否可用
 	if _, err := exec.LookPath("git"); err != nil {
-		return fmt.Errorf("未找到git命令，请确保已安装git")
+		return "", fmt.Errorf("未找到git命令，请确保已安装git")
 	}
 
-	// 获取项目根目录（相对于scripts目录的上级）
-	parentDir := ".."
-	absParentDir, err := filepath.Abs(parentDir)
+	absRepoDir, err := filepath.Abs(repoDir)
 	if err != nil {
-		return fmt.Errorf("获取项目路径失败: %v", err)
+		return "", fmt.Errorf("获取项目路径失败: %v", err)
 	}
 
 	// 检查.git目录是否存在
-	gitDir := filepath.Join(absParentDir, ".git")
+	gitDir := filepath.Join(absRepoDir, ".git")
 	if _, err := os.Stat(gitDir); err != nil {
-		return fmt.Errorf("未找到.git目录，请确保在正确的项目目录中")
+		return "", fmt.Errorf("未找到git仓库（%s），请确保在正确的项目目录中", absRepoDir)
 	}
 
-	// 检查是否有未提交的更改
-	cmd := exec.Command("git", "-C", absParentDir, "status", "--porcelain")
+	return absRepoDir, nil
+}
+
+// branchExists 检查本地分支是否存在
+func branchExists(repoDir, branchName string) bool {
+	cmd := exec.Command("git", "-C", repoDir, "rev-parse", "--verify", branchName)
+	return cmd.Run() == nil
+}
+
+// nextAvailableBranch 从给定名称开始，自动递增后缀直到找到不存在的分支名称
+func nextAvailableBranch(repoDir, baseBranchName string) string {
+	branchName := baseBranchName
+	counter := 1
+	for branchExists(repoDir, branchName) {
+		counter++
+		branchName = fmt.Sprintf("%s-%d", baseBranchName, counter)
+	}
+	return branchName
+}
+
+// submitOptions 提交PR的参数
+type submitOptions struct {
+	repoDir    string // 项目根目录（绝对路径）
+	newBranch  bool   // true: 新建分支提交新的PR；false: 提交到当前分支已有的PR
+	branchName string // 分支名称
+	message    string // 提交信息
+}
+
+// commitAndPush 按提交参数执行 创建分支/添加/提交/推送，返回PR链接
+func commitAndPush(opts submitOptions) (string, error) {
+	if opts.newBranch {
+		// 创建新分支
+		fmt.Printf("正在创建分支: %s...\n", opts.branchName)
+		cmd := exec.Command("git", "-C", opts.repoDir, "checkout", "-b", opts.branchName)
+		if err := cmd.Run(); err != nil {
+			return "", fmt.Errorf("创建分支失败: %v", err)
+		}
+	}
+
+	// 添加更改
+	fmt.Println("正在添加文件...")
+	cmd := exec.Command("git", "-C", opts.repoDir, "add", "tmdb_config/")
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("添加文件失败: %v", err)
+	}
+	if err := addOverlaySnapshots(opts.repoDir); err != nil {
+		return "", err
+	}
+
+	// 提交更改
+	fmt.Println("正在提交更改...")
+	cmd = exec.Command("git", "-C", opts.repoDir, "commit", "-m", opts.message)
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("提交失败: %v", err)
+	}
+
+	// 推送到远程
+	fmt.Println("正在推送到远程...")
+	if opts.newBranch {
+		// 新分支需要设置upstream
+		cmd = exec.Command("git", "-C", opts.repoDir, "push", "-u", "origin", opts.branchName)
+	} else {
+		// 现有分支直接推送
+		cmd = exec.Command("git", "-C", opts.repoDir, "push")
+	}
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("推送失败: %v", err)
+	}
+
+	// 提供结果信息
+	fmt.Println("\n" + strings.Repeat("=", 60))
+	fmt.Println("✓ 提交成功！")
+	fmt.Println(strings.Repeat("=", 60))
+
+	prURL := fmt.Sprintf("https://github.com/xylplm/media-saber-ctmd/compare/main...%s", opts.branchName)
+	if opts.newBranch {
+		fmt.Printf("\n分支已推送到: origin/%s\n", opts.branchName)
+		fmt.Println("请访问以下链接创建PR:")
+		fmt.Println(prURL)
+	} else {
+		fmt.Printf("\n修改已推送到分支: %s\n", opts.branchName)
+		fmt.Println("如果该分支已有关联的PR，修改会自动出现在PR中。")
+		fmt.Printf("PR链接: %s\n", prURL)
+	}
+
+	return prURL, nil
+}
+
+// addOverlaySnapshots 添加使用覆盖文件的条目的快照，以及已提交的快照的修改和删除
+// 快照与 tmdb_config 中的数据大小相当，全部提交会让仓库随每次获取成倍增长，因此 tmdb_upstream/ 在 .gitignore 中，
+// 其他条目的快照只保存在本地，作为刷新和比较的基准；覆盖文件需要在快照上应用才能打包，这些快照必须提交
+func addOverlaySnapshots(repoDir string) error {
+	if !checkDirectoryExists(filepath.Join(repoDir, upstreamDirName)) {
+		return nil
+	}
+	local := &TMDBFetcher{outputDir: filepath.Join(repoDir, "tmdb_config")}
+	keys, err := listMediaKeys(local.outputDir)
+	if err != nil {
+		return err
+	}
+	var snapshots []string
+	for _, key := range keys {
+		if local.hasOverlay(key.mediaType, key.mediaID) && checkDirectoryExists(local.snapshotDir(key.mediaType, key.mediaID)) {
+			snapshots = append(snapshots, path.Join(upstreamDirName, key.mediaType, key.mediaID)+"/")
+		}
+	}
+	if len(snapshots) > 0 {
+		// tmdb_upstream/ 被忽略，需要 -f
+		args := append([]string{"-C", repoDir, "add", "-f", "--"}, snapshots...)
+		if err := exec.Command("git", args...).Run(); err != nil {
+			return fmt.Errorf("添加快照失败: %v", err)
+		}
+	}
+	// -u 只处理已提交的文件，如条目不再使用覆盖文件后删除的快照
+	if err := exec.Command("git", "-C", repoDir, "add", "-u", "--", upstreamDirName+"/").Run(); err != nil {
+		return fmt.Errorf("添加快照失败: %v", err)
+	}
+	return nil
+}
+
+// currentBranch 获取当前分支名称
+func currentBranch(repoDir string) (string, error) {
+	cmd := exec.Command("git", "-C", repoDir, "rev-parse", "--abbrev-ref", "HEAD")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("获取当前分支失败: %v", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// pendingChanges 获取未提交的更改列表（git status --porcelain 输出）
+func pendingChanges(repoDir string) string {
+	cmd := exec.Command("git", "-C", repoDir, "status", "--porcelain")
 	output, _ := cmd.Output()
-	if len(output) == 0 {
+	return string(output)
+}
+
+// submitPullRequest 提交PR到GitHub
+func submitPullRequest(reader *bufio.Reader, repoDir string) error {
+	fmt.Println("\n" + strings.Repeat("=", 60))
+	fmt.Println("📤 一键提交PR到 GitHub")
+	fmt.Println(strings.Repeat("=", 60))
+
+	absRepoDir, err := resolveGitRepo(repoDir)
+	if err != nil {
+		return err
+	}
+
+	// 检查是否有未提交的更改
+	changes := pendingChanges(absRepoDir)
+	if len(changes) == 0 {
 		fmt.Println("✓ 当前没有需要提交的更改")
 		return nil
 	}
 
 	fmt.Println("\n检测到以下更改:")
-	fmt.Println(string(output))
+	fmt.Println(changes)
 
 	// 确认提交
 	fmt.Print("\n确认提交这些更改? (y/n): ")
@@ -436,58 +875,33 @@ func submitPullRequest(reader *bufio.Reader) error {
 	modeInput, _ := reader.ReadString('\n')
 	mode := strings.TrimSpace(modeInput)
 
-	var branchName, message string
+	opts := submitOptions{repoDir: absRepoDir}
 
 	if mode == "1" {
 		// 新建分支模式
+		opts.newBranch = true
 		fmt.Print("\n请输入分支名称 (默认: 自动生成): ")
 		branchInput, _ := reader.ReadString('\n')
-		branchName = strings.TrimSpace(branchInput)
-		
-		if branchName == "" {
-			// 自动生成分支名称
-			baseBranchName := "update-tmdb-config"
-			branchName = baseBranchName
-			counter := 1
-			
-			// 检查分支是否存在，如果存在则自动递增
+		opts.branchName = strings.TrimSpace(branchInput)
+
+		if opts.branchName == "" {
+			// 自动生成分支名称，如果已存在则自动递增
 			fmt.Println("正在生成唯一的分支名称...")
-			for {
-				cmd := exec.Command("git", "-C", absParentDir, "rev-parse", "--verify", branchName)
-				if err := cmd.Run(); err != nil {
-					// 分支不存在，可以使用
-					break
-				}
-				// 分支已存在，尝试下一个名称
-				counter++
-				branchName = fmt.Sprintf("%s-%d", baseBranchName, counter)
-			}
-			fmt.Printf("✓ 已自动生成分支名称: %s\n", branchName)
+			opts.branchName = nextAvailableBranch(absRepoDir, "update-tmdb-config")
+			fmt.Printf("✓ 已自动生成分支名称: %s\n", opts.branchName)
 		} else {
 			// 用户输入了分支名称，检查是否已存在
 			fmt.Println("正在检查分支是否存在...")
-			cmd := exec.Command("git", "-C", absParentDir, "rev-parse", "--verify", branchName)
-			if err := cmd.Run(); err == nil {
-				// 分支已存在
-				fmt.Printf("\n⚠️  警告: 分支 '%s' 已存在\n", branchName)
+			if branchExists(absRepoDir, opts.branchName) {
+				fmt.Printf("\n⚠️  警告: 分支 '%s' 已存在\n", opts.branchName)
 				fmt.Print("是否要自动创建一个新分支? (y/n): ")
 				autoCreateInput, _ := reader.ReadString('\n')
 				if strings.TrimSpace(strings.ToLower(autoCreateInput)) == "y" {
 					// 自动生成新分支名称
-					baseBranchName := branchName
-					counter := 1
-					originalBranchName := branchName
+					originalBranchName := opts.branchName
 					fmt.Println("正在生成唯一的分支名称...")
-					for {
-						cmd := exec.Command("git", "-C", absParentDir, "rev-parse", "--verify", branchName)
-						if err := cmd.Run(); err != nil {
-							// 分支不存在，可以使用
-							break
-						}
-						counter++
-						branchName = fmt.Sprintf("%s-%d", baseBranchName, counter)
-					}
-					fmt.Printf("✓ 已创建新分支: %s (原分支名: %s)\n", branchName, originalBranchName)
+					opts.branchName = nextAvailableBranch(absRepoDir, originalBranchName)
+					fmt.Printf("✓ 已创建新分支: %s (原分支名: %s)\n", opts.branchName, originalBranchName)
 				} else {
 					fmt.Println("已取消，请使用其他分支名称或选择模式2提交到现有分支")
 					return nil
@@ -495,95 +909,34 @@ func submitPullRequest(reader *bufio.Reader) error {
 			}
 		}
 
-		// 创建新分支
-		fmt.Printf("正在创建分支: %s...\n", branchName)
-		cmd = exec.Command("git", "-C", absParentDir, "checkout", "-b", branchName)
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("创建分支失败: %v", err)
-		}
-
-		// 输入提交信息
-		fmt.Print("请输入提交信息 (默认: Update TMDB config metadata): ")
-		messageInput, _ := reader.ReadString('\n')
-		message = strings.TrimSpace(messageInput)
-		if message == "" {
-			message = "Update TMDB config metadata"
-		}
-
 	} else if mode == "2" {
 		// 提交到现有分支模式
-		// 获取当前分支名称
-		cmd := exec.Command("git", "-C", absParentDir, "rev-parse", "--abbrev-ref", "HEAD")
-		branchOutput, err := cmd.Output()
+		opts.branchName, err = currentBranch(absRepoDir)
 		if err != nil {
-			return fmt.Errorf("获取当前分支失败: %v", err)
+			return err
 		}
-		branchName = strings.TrimSpace(string(branchOutput))
 
-		if branchName == "main" || branchName == "master" {
+		if opts.branchName == "main" || opts.branchName == "master" {
 			return fmt.Errorf("不能在main/master分支上提交，请先创建新分支")
 		}
 
-		fmt.Printf("当前分支: %s\n", branchName)
-
-		// 输入提交信息
-		fmt.Print("请输入提交信息 (默认: Update TMDB config metadata): ")
-		messageInput, _ := reader.ReadString('\n')
-		message = strings.TrimSpace(messageInput)
-		if message == "" {
-			message = "Update TMDB config metadata"
-		}
+		fmt.Printf("当前分支: %s\n", opts.branchName)
 
 	} else {
 		return fmt.Errorf("无效的选项")
 	}
 
-	// 添加更改
-	fmt.Println("正在添加文件...")
-	cmd = exec.Command("git", "-C", absParentDir, "add", "tmdb_config/")
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("添加文件失败: %v", err)
-	}
-
-	// 提交更改
-	fmt.Println("正在提交更改...")
-	cmd = exec.Command("git", "-C", absParentDir, "commit", "-m", message)
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("提交失败: %v", err)
+	// 输入提交信息
+	fmt.Print("请输入提交信息 (默认: Update TMDB config metadata): ")
+	messageInput, _ := reader.ReadString('\n')
+	opts.message = strings.TrimSpace(messageInput)
+	if opts.message == "" {
+		opts.message = "Update TMDB config metadata"
 	}
 
-	// 推送到远程
-	fmt.Println("正在推送到远程...")
-	if mode == "1" {
-		// 新分支需要设置upstream
-		cmd = exec.Command("git", "-C", absParentDir, "push", "-u", "origin", branchName)
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("推送失败: %v", err)
-		}
-	} else {
-		// 现有分支直接推送
-		cmd = exec.Command("git", "-C", absParentDir, "push")
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("推送失败: %v", err)
-		}
-	}
-
-	// 提供结果信息
-	fmt.Println("\n" + strings.Repeat("=", 60))
-	fmt.Println("✓ 提交成功！")
-	fmt.Println(strings.Repeat("=", 60))
-
-	var prURL string
-	if mode == "1" {
-		fmt.Printf("\n分支已推送到: origin/%s\n", branchName)
-		prURL = fmt.Sprintf("https://github.com/xylplm/media-saber-ctmd/compare/main...%s", branchName)
-		fmt.Println("请访问以下链接创建PR:")
-		fmt.Println(prURL)
-	} else {
-		fmt.Printf("\n修改已推送到分支: %s\n", branchName)
-		fmt.Println("如果该分支已有关联的PR，修改会自动出现在PR中。")
-		prURL = fmt.Sprintf("https://github.com/xylplm/media-saber-ctmd/compare/main...%s", branchName)
-		fmt.Printf("PR链接: %s\n", prURL)
+	prURL, err := commitAndPush(opts)
+	if err != nil {
+		return err
 	}
 
 	// 询问是否打开浏览器
@@ -600,64 +953,54 @@ func submitPullRequest(reader *bufio.Reader) error {
 	return nil
 }
 
+// confirm 询问用户确认，assumeYes为true时直接返回true
+func confirm(reader *bufio.Reader, prompt string, assumeYes bool) bool {
+	fmt.Print(prompt)
+	if assumeYes {
+		fmt.Println("y")
+		return true
+	}
+	input, _ := reader.ReadString('\n')
+	return strings.TrimSpace(strings.ToLower(input)) == "y"
+}
+
 // syncFromMainRepo 从主库同步最新代码
-func syncFromMainRepo(reader *bufio.Reader) error {
+func syncFromMainRepo(reader *bufio.Reader, repoDir string, assumeYes bool) error {
 	fmt.Println("\n" + strings.Repeat("=", 60))
 	fmt.Println("🔄 从主库同步最新代码")
 	fmt.Println("主库: https://github.com/xylplm/media-saber-ctmd")
 	fmt.Println(strings.Repeat("=", 60))
 
-	// 检查git是否可用
-	if _, err := exec.LookPath("git"); err != nil {
-		return fmt.Errorf("未找到git命令，请确保已安装git")
-	}
-
-	// 获取项目根目录（相对于scripts目录的上级）
-	parentDir := ".."
-	absParentDir, err := filepath.Abs(parentDir)
+	absRepoDir, err := resolveGitRepo(repoDir)
 	if err != nil {
-		return fmt.Errorf("获取项目路径失败: %v", err)
-	}
-
-	// 检查.git目录是否存在
-	gitDir := filepath.Join(absParentDir, ".git")
-	if _, err := os.Stat(gitDir); err != nil {
-		return fmt.Errorf("未找到git仓库（%s），请确保在正确的项目目录中", absParentDir)
+		return err
 	}
 
 	// 检查是否有未提交的更改
-	cmd := exec.Command("git", "-C", absParentDir, "status", "--porcelain")
-	output, _ := cmd.Output()
-	if len(output) > 0 {
+	if changes := pendingChanges(absRepoDir); len(changes) > 0 {
 		fmt.Println("\n⚠️  警告: 您有未提交的更改:")
-		fmt.Println(string(output))
-		fmt.Print("继续同步会丢失这些更改，是否继续? (y/n): ")
-		input, _ := reader.ReadString('\n')
-		if strings.TrimSpace(strings.ToLower(input)) != "y" {
+		fmt.Println(changes)
+		if !confirm(reader, "继续同步会丢失这些更改，是否继续? (y/n): ", assumeYes) {
 			fmt.Println("已取消")
 			return nil
 		}
 	}
 
 	// 获取当前分支
-	cmd = exec.Command("git", "-C", absParentDir, "rev-parse", "--abbrev-ref", "HEAD")
-	currentBranchOutput, err := cmd.Output()
+	branch, err := currentBranch(absRepoDir)
 	if err != nil {
-		return fmt.Errorf("获取当前分支失败: %v", err)
+		return err
 	}
-	currentBranch := strings.TrimSpace(string(currentBranchOutput))
 
 	// 如果不在main分支，提示用户
-	if currentBranch != "main" && currentBranch != "master" {
-		fmt.Printf("\n⚠️  当前分支: %s\n", currentBranch)
-		fmt.Print("同步建议在main/master分支上进行，是否切换到main分支? (y/n): ")
-		input, _ := reader.ReadString('\n')
-		if strings.TrimSpace(strings.ToLower(input)) == "y" {
+	if branch != "main" && branch != "master" {
+		fmt.Printf("\n⚠️  当前分支: %s\n", branch)
+		if confirm(reader, "同步建议在main/master分支上进行，是否切换到main分支? (y/n): ", assumeYes) {
 			fmt.Println("正在切换到main分支...")
-			cmd = exec.Command("git", "-C", absParentDir, "checkout", "main")
+			cmd := exec.Command("git", "-C", absRepoDir, "checkout", "main")
 			if err := cmd.Run(); err != nil {
 				// 尝试master
-				cmd = exec.Command("git", "-C", absParentDir, "checkout", "master")
+				cmd = exec.Command("git", "-C", absRepoDir, "checkout", "master")
 				if err := cmd.Run(); err != nil {
 					return fmt.Errorf("切换分支失败: %v", err)
 				}
@@ -668,11 +1011,11 @@ func syncFromMainRepo(reader *bufio.Reader) error {
 
 	// 检查upstream是否存在，如果不存在则添加
 	fmt.Println("\n正在检查upstream配置...")
-	cmd = exec.Command("git", "-C", absParentDir, "remote", "get-url", "upstream")
+	cmd := exec.Command("git", "-C", absRepoDir, "remote", "get-url", "upstream")
 	upstreamURL, _ := cmd.Output()
 	if len(upstreamURL) == 0 {
 		fmt.Println("⚠️  未找到upstream，正在添加主库...")
-		cmd = exec.Command("git", "-C", absParentDir, "remote", "add", "upstream", "https://github.com/xylplm/media-saber-ctmd.git")
+		cmd = exec.Command("git", "-C", absRepoDir, "remote", "add", "upstream", "https://github.com/xylplm/media-saber-ctmd.git")
 		if err := cmd.Run(); err != nil {
 			return fmt.Errorf("添加upstream失败: %v", err)
 		}
@@ -683,7 +1026,7 @@ func syncFromMainRepo(reader *bufio.Reader) error {
 
 	// 获取upstream的最新更新
 	fmt.Println("\n正在获取upstream最新代码...")
-	cmd = exec.Command("git", "-C", absParentDir, "fetch", "upstream")
+	cmd = exec.Command("git", "-C", absRepoDir, "fetch", "upstream")
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("获取upstream更新失败: %v", err)
 	}
@@ -691,11 +1034,11 @@ func syncFromMainRepo(reader *bufio.Reader) error {
 
 	// 同步main分支
 	fmt.Println("正在同步main分支...")
-	cmd = exec.Command("git", "-C", absParentDir, "pull", "upstream", "main")
+	cmd = exec.Command("git", "-C", absRepoDir, "pull", "upstream", "main")
 	if err := cmd.Run(); err != nil {
 		// 尝试master
 		fmt.Println("main分支拉取失败，尝试master分支...")
-		cmd = exec.Command("git", "-C", absParentDir, "pull", "upstream", "master")
+		cmd = exec.Command("git", "-C", absRepoDir, "pull", "upstream", "master")
 		if err := cmd.Run(); err != nil {
 			return fmt.Errorf("同步代码失败: %v", err)
 		}
@@ -707,7 +1050,7 @@ func syncFromMainRepo(reader *bufio.Reader) error {
 	fmt.Println(strings.Repeat("=", 60))
 
 	// 获取最新的commit信息
-	cmd = exec.Command("git", "-C", absParentDir, "log", "-1", "--oneline")
+	cmd = exec.Command("git", "-C", absRepoDir, "log", "-1", "--oneline")
 	logOutput, _ := cmd.Output()
 	if len(logOutput) > 0 {
 		fmt.Printf("\n最新提交: %s\n", string(logOutput))
@@ -717,10 +1060,15 @@ func syncFromMainRepo(reader *bufio.Reader) error {
 }
 
 func main() {
-	fmt.Println(banner)
+	// 带参数运行时进入命令行模式，否则进入交互菜单
+	if len(os.Args) > 1 {
+		os.Exit(runCLI(os.Args[1:]))
+	}
+
+	fmt.Print(banner + "\n")
 
 	// 初始化获取器
-	fetcher, err := NewTMDBFetcher("../cli/config.json")
+	fetcher, err := NewTMDBFetcher(defaultConfigPath, defaultOutputDir)
 	if err != nil {
 		fmt.Printf("错误: %v\n", err)
 		fmt.Println("\n按回车键退出...")
@@ -736,8 +1084,10 @@ func main() {
 		fmt.Println("  1. 从主库同步最新代码(修改前)")
 		fmt.Println("  2. 获取电影/电视剧数据")
 		fmt.Println("  3. 一键提交修改到PR(修改后)")
+		fmt.Println("  4. 批量获取电影/电视剧数据(从列表文件)")
+		fmt.Println("  5. 刷新已有数据(保留已维护的修改)")
 		fmt.Println("  q. 退出")
-		fmt.Print("\n请输入选项 (1/2/3/q): ")
+		fmt.Print("\n请输入选项 (1/2/3/4/5/q): ")
 
 		mainChoice, _ := reader.ReadString('\n')
 		mainChoice = strings.TrimSpace(strings.ToLower(mainChoice))
@@ -745,25 +1095,15 @@ func main() {
 		switch mainChoice {
 		case "1":
 			// 从主库同步最新代码
-			if err := syncFromMainRepo(reader); err != nil {
+			if err := syncFromMainRepo(reader, defaultRepoDir, false); err != nil {
 				fmt.Printf("\n错误: %v\n", err)
 			}
 
 		case "2":
 			// 原有的数据获取流程
 			for {
-				// 获取媒体类型
-				mediaType, err := getMediaType(reader)
-				if err != nil {
-					fmt.Printf("错误: %v\n", err)
-					break
-				}
-				if mediaType == "quit" {
-					break
-				}
-
-				// 获取媒体ID
-				mediaID, err := getMediaID(reader)
+				// 获取媒体类型和ID
+				mediaType, mediaID, err := fetcher.promptMedia(reader)
 				if err != nil {
 					fmt.Printf("错误: %v\n", err)
 					break
@@ -773,15 +1113,17 @@ func main() {
 				}
 
 				// 获取并保存数据
-				var fetchErr error
-				if mediaType == "movie" {
-					fetchErr = fetcher.fetchAndSaveMovie(mediaID)
-				} else {
-					fetchErr = fetcher.fetchAndSaveTV(mediaID)
-				}
-
-				if fetchErr != nil {
+				if fetchErr := fetcher.fetchAndSave(mediaType, mediaID); fetchErr != nil {
 					fmt.Printf("\n错误: %v\n", fetchErr)
+					// ID不存在或API Key无效时重试没有意义
+					if errors.Is(fetchErr, errNotFound) {
+						fmt.Println("请检查媒体类型和TMDB ID是否正确")
+						continue
+					}
+					if errors.Is(fetchErr, errUnauthorized) {
+						fmt.Println("请检查配置文件中的 tmdb_api_key")
+						break
+					}
 					fmt.Print("是否重试? (y/n): ")
 					input, _ := reader.ReadString('\n')
 					input = strings.TrimSpace(strings.ToLower(input))
@@ -800,9 +1142,36 @@ func main() {
 
 		case "3":
 			// 提交PR
-			if err := submitPullRequest(reader); err != nil {
+			if err := submitPullRequest(reader, defaultRepoDir); err != nil {
+				fmt.Printf("\n错误: %v\n", err)
+			}
+
+		case "4":
+			// 批量获取
+			fmt.Println("\n列表文件每行一条记录，例如: movie 842675、tv/95480、TMDB链接或 tt1234567")
+			fmt.Print("请输入列表文件路径: ")
+			path, _ := reader.ReadString('\n')
+			path = strings.Trim(strings.TrimSpace(path), "\"")
+			if path == "" {
+				fmt.Println("路径不能为空")
+				break
+			}
+			if _, err := fetcher.runBatchFile(path); err != nil {
+				fmt.Printf("\n错误: %v\n", err)
+			}
+
+		case "5":
+			// 刷新已有数据
+			mediaType, mediaID, err := fetcher.promptMedia(reader)
+			if err != nil || mediaID == "quit" {
+				break
+			}
+			results, err := fetcher.refreshMedia(mediaType, mediaID)
+			if err != nil {
 				fmt.Printf("\n错误: %v\n", err)
+				break
 			}
+			printRefreshReport(mediaType, mediaID, results)
 
 		case "q":
 			fmt.Println("\n感谢使用，再见!")
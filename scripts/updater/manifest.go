@@ -0,0 +1,343 @@
+package updater
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// 发布包的文件名和目录结构，tmdb-manager pack 生成发布包时也使用这些定义
+const (
+	ManifestName   = "manifest.json"
+	SignatureExt   = ".sig"                  // 签名文件的扩展名，内容为 base64 编码的 ed25519 签名
+	ConfigDirName  = "tmdb_config"           // 数据目录名
+	RootDir        = "media-saber-ctmd-main" // 压缩包的顶层目录，Media Saber 按此目录解压
+	DeltaIndexName = "delta.json"            // 增量包顶层目录中的文件列表
+	fingerprint    = "sha256:"               // 公钥指纹的前缀
+)
+
+// Manifest 发布包的 manifest.json，与压缩包一起发布
+// Media Saber 可以据此判断哪些条目有变化，并单独校验每个文件
+type Manifest struct {
+	Version    string         `json:"version"`               // 发布版本号，与 Release 的 tag 相同
+	Commit     string         `json:"commit,omitempty"`      // 打包时的提交
+	SigningKey string         `json:"signing_key,omitempty"` // 签名公钥的指纹，由 sign 命令写入
+	Archive    string         `json:"archive"`
+	Size       int64          `json:"size"`
+	SHA256     string         `json:"sha256"`
+	Files      int            `json:"files"`
+	Counts     map[string]int `json:"counts"`          // 各媒体类型的条目数
+	Delta      *Delta         `json:"delta,omitempty"` // 相对上一个版本的增量包，由 pack --base 生成
+	Entries    []Entry        `json:"entries"`
+}
+
+// Delta 相对上一个版本的增量包
+type Delta struct {
+	Archive string `json:"archive"`
+	From    string `json:"from"` // 增量包基于的版本
+	Size    int64  `json:"size"`
+	SHA256  string `json:"sha256"`
+	Changed int    `json:"changed"` // 新增或修改的文件数
+	Deleted int    `json:"deleted"` // 删除的文件数
+}
+
+// Entry 发布包中的一个条目
+type Entry struct {
+	Type       string `json:"type"`
+	ID         string `json:"id"`
+	Title      string `json:"title"`
+	Overlay    bool   `json:"overlay,omitempty"`     // 使用覆盖文件维护
+	Commit     string `json:"commit,omitempty"`      // 最后修改该条目的提交
+	CommitTime string `json:"commit_time,omitempty"` // 该提交的时间 (RFC3339)
+	Files      []File `json:"files"`
+}
+
+// File 条目中的一个文件，Path 相对于条目目录
+type File struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// DeltaIndex 增量包中的 delta.json，路径均相对于 tmdb_config
+type DeltaIndex struct {
+	From    string   `json:"from"`
+	To      string   `json:"to"`
+	Changed []string `json:"changed"`
+	Deleted []string `json:"deleted"`
+}
+
+// ParseManifest 解析 manifest.json
+func ParseManifest(data []byte) (*Manifest, error) {
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %v", ManifestName, err)
+	}
+	if m.Version == "" || m.Archive == "" {
+		return nil, fmt.Errorf("%s 缺少版本号或压缩包信息", ManifestName)
+	}
+	return &m, nil
+}
+
+// LoadManifest 读取 manifest.json
+func LoadManifest(manifestPath string) (*Manifest, error) {
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	return ParseManifest(data)
+}
+
+// IsDeltaArchive 判断压缩包是否为 manifest 中记录的增量包
+func (m *Manifest) IsDeltaArchive(archivePath string) bool {
+	return m.Delta != nil && filepath.Base(archivePath) == m.Delta.Archive
+}
+
+// FileHashes 返回全部文件的 SHA256，键为相对于 tmdb_config 的路径
+func (m *Manifest) FileHashes() map[string]string {
+	hashes := make(map[string]string)
+	for _, entry := range m.Entries {
+		for _, file := range entry.Files {
+			hashes[path.Join(entry.Type, entry.ID, file.Path)] = file.SHA256
+		}
+	}
+	return hashes
+}
+
+// Fingerprint 返回公钥指纹，格式与 manifest.json 中的 signing_key 相同
+func Fingerprint(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return fingerprint + hex.EncodeToString(sum[:])
+}
+
+// VerifySignature 校验 base64 编码的 ed25519 签名
+func VerifySignature(pub ed25519.PublicKey, data, encodedSignature []byte) error {
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encodedSignature)))
+	if err != nil {
+		return fmt.Errorf("签名格式错误: %v", err)
+	}
+	if !ed25519.Verify(pub, data, signature) {
+		return fmt.Errorf("签名无效，文件可能被篡改或不是由该公钥签名")
+	}
+	return nil
+}
+
+// CheckArchive 校验压缩包的大小和 SHA256 与 manifest 中的记录一致
+func CheckArchive(name string, data []byte, size int64, sha string) error {
+	sum := sha256.Sum256(data)
+	if int64(len(data)) != size || hex.EncodeToString(sum[:]) != sha {
+		return fmt.Errorf("%s 的大小或 SHA256 与 %s 中的记录不一致", name, ManifestName)
+	}
+	return nil
+}
+
+// IsSafeRelPath 检查压缩包或 delta.json 中的路径，拒绝绝对路径和指向目录之外的路径
+func IsSafeRelPath(p string) bool {
+	return p != "" && p != "." && path.Clean(p) == p && !path.IsAbs(p) && p != ".." && !strings.HasPrefix(p, "../")
+}
+
+// ArchiveFile 压缩包中 tmdb_config 目录下的一个文件
+type ArchiveFile struct {
+	Path string // 相对于 tmdb_config 的路径，使用 / 分隔
+	Data []byte
+}
+
+// ReadArchive 读取发布包或增量包，返回 tmdb_config 中的文件和顶层目录中的其他文件 (如增量包的 delta.json)
+// 不在顶层目录中或指向目录之外的路径视为错误
+func ReadArchive(archive []byte) ([]ArchiveFile, map[string][]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, nil, fmt.Errorf("解压失败: %v", err)
+	}
+	tr := tar.NewReader(gz)
+	rootPrefix := RootDir + "/"
+	configPrefix := path.Join(RootDir, ConfigDirName) + "/"
+
+	var files []ArchiveFile
+	rootFiles := make(map[string][]byte)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("解压失败: %v", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if !strings.HasPrefix(header.Name, rootPrefix) || !IsSafeRelPath(header.Name) {
+			return nil, nil, fmt.Errorf("压缩包中的路径不正确: %s", header.Name)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("解压失败: %v", err)
+		}
+		if relPath, ok := strings.CutPrefix(header.Name, configPrefix); ok {
+			files = append(files, ArchiveFile{Path: relPath, Data: data})
+		} else {
+			rootFiles[strings.TrimPrefix(header.Name, rootPrefix)] = data
+		}
+	}
+	return files, rootFiles, nil
+}
+
+// extractArchive 把压缩包中 tmdb_config 目录下的文件解压到 destDir
+func extractArchive(archive []byte, destDir string) error {
+	files, _, err := ReadArchive(archive)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := writeFile(filepath.Join(destDir, filepath.FromSlash(file.Path)), bytes.NewReader(file.Data)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ApplyDelta 在 configDir 中应用 m 中记录的增量包，configDir 应为增量包所基于版本的数据 (通常是其副本)
+// 先校验增量包的大小、SHA256、delta.json 的版本和路径，应用后按 m 校验全部文件；
+// 校验之前的错误不会改动 configDir，之后的错误可能留下部分修改
+func ApplyDelta(configDir string, archive []byte, m *Manifest) (*DeltaIndex, error) {
+	delta := m.Delta
+	if delta == nil {
+		return nil, fmt.Errorf("%s 中没有增量包的记录", ManifestName)
+	}
+	if err := CheckArchive(delta.Archive, archive, delta.Size, delta.SHA256); err != nil {
+		return nil, err
+	}
+	files, rootFiles, err := ReadArchive(archive)
+	if err != nil {
+		return nil, err
+	}
+	indexData, ok := rootFiles[DeltaIndexName]
+	if !ok {
+		return nil, fmt.Errorf("增量包中缺少 %s", DeltaIndexName)
+	}
+	var index DeltaIndex
+	if err := json.Unmarshal(indexData, &index); err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %v", DeltaIndexName, err)
+	}
+	if index.From != delta.From || index.To != m.Version {
+		return nil, fmt.Errorf("增量包 (%s → %s) 与 %s (%s → %s) 的版本不一致", index.From, index.To, ManifestName, delta.From, m.Version)
+	}
+	for _, relPath := range index.Deleted {
+		if !IsSafeRelPath(relPath) {
+			return nil, fmt.Errorf("%s 中的路径不正确: %s", DeltaIndexName, relPath)
+		}
+	}
+
+	for _, relPath := range index.Deleted {
+		if err := os.Remove(filepath.Join(configDir, filepath.FromSlash(relPath))); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("删除 %s 失败: %v", relPath, err)
+		}
+		// 删除文件后为空的目录一并删除，目录不为空时 os.Remove 失败，不再向上处理
+		for dir := path.Dir(relPath); dir != "."; dir = path.Dir(dir) {
+			if os.Remove(filepath.Join(configDir, filepath.FromSlash(dir))) != nil {
+				break
+			}
+		}
+	}
+	for _, file := range files {
+		if err := writeFile(filepath.Join(configDir, filepath.FromSlash(file.Path)), bytes.NewReader(file.Data)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := VerifyTree(configDir, m); err != nil {
+		return nil, fmt.Errorf("应用增量包后的数据与版本 %s 不一致，可能不是版本 %s 的数据: %v", m.Version, delta.From, err)
+	}
+	return &index, nil
+}
+
+// writeFile 写入文件，自动创建上级目录
+func writeFile(filePath string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %v", err)
+	}
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("写入 %s 失败: %v", filePath, err)
+	}
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		return fmt.Errorf("写入 %s 失败: %v", filePath, err)
+	}
+	return file.Close()
+}
+
+// CompareFiles 比较文件与 manifest 中记录的 SHA256，返回排序后的不一致说明
+// complete 为 true 时 files 应包含全部数据文件，manifest 中有但 files 中没有的文件也视为不一致
+func CompareFiles(files []ArchiveFile, m *Manifest, complete bool) []string {
+	expected := m.FileHashes()
+	var problems []string
+	for _, file := range files {
+		want, ok := expected[file.Path]
+		if !ok {
+			problems = append(problems, file.Path+": 不在 "+ManifestName+" 中")
+			continue
+		}
+		delete(expected, file.Path)
+		if sum := sha256.Sum256(file.Data); hex.EncodeToString(sum[:]) != want {
+			problems = append(problems, file.Path+": SHA256 不一致")
+		}
+	}
+	if complete {
+		for relPath := range expected {
+			problems = append(problems, relPath+": 缺少该文件")
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+// VerifyTree 校验目录中的数据文件与 manifest 完全一致: 没有多余或缺少的文件，每个文件的 SHA256 相同
+// 隐藏文件和目录不参与校验
+func VerifyTree(dir string, m *Manifest) error {
+	var files []ArchiveFile
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("读取 %s 失败: %v", p, err)
+		}
+		if p != dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return fmt.Errorf("读取 %s 失败: %v", p, err)
+		}
+		files = append(files, ArchiveFile{Path: filepath.ToSlash(rel), Data: data})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if problems := CompareFiles(files, m, true); len(problems) > 0 {
+		return fmt.Errorf("%d 处与 %s 不一致:\n  %s", len(problems), ManifestName, strings.Join(problems, "\n  "))
+	}
+	return nil
+}
@@ -0,0 +1,386 @@
+// Package updater 订阅 media-saber-ctmd 的发布，下载、校验并安装 tmdb_config 数据
+//
+// 发布地址下应有 manifest.json 及其中记录的压缩包，默认为主库最新 Release 的下载地址；
+// 本地测试时可以用任意静态文件服务提供 tmdb-manager pack 生成的目录。
+//
+// 安装目录的结构:
+//
+//	<Dir>/current/manifest.json   当前版本的 manifest.json
+//	<Dir>/current/tmdb_config/    当前版本的数据
+//	<Dir>/previous/               上一个版本，结构与 current 相同，用于回滚
+//	<Dir>/.lock                   更新或回滚进行中的锁文件，同一安装目录同时只能运行一个
+package updater
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultFeedURL 主库最新 Release 的下载地址
+const DefaultFeedURL = "https://github.com/xylplm/media-saber-ctmd/releases/latest/download"
+
+// 安装目录中的子目录
+const (
+	currentDirName  = "current"
+	previousDirName = "previous"
+	stagingPrefix   = ".staging-" // 下载和解压使用的暂存目录，位于安装目录中，保证重命名是原子操作
+	lockFileName    = ".lock"
+)
+
+// staleLockAge 锁文件超过这个时间仍未删除时视为上次运行被中断后留下的
+const staleLockAge = 2 * time.Hour
+
+// Updater 从发布地址检查、下载并安装数据
+type Updater struct {
+	FeedURL   string            // 发布地址，其下有 manifest.json 和压缩包
+	Dir       string            // 安装目录
+	PublicKey ed25519.PublicKey // 不为 nil 时校验 manifest.json 和压缩包的签名
+	Client    *http.Client
+	Logf      func(format string, a ...interface{}) // 输出进度信息，为 nil 时不输出
+}
+
+// Result 一次更新的结果
+type Result struct {
+	From    string // 更新前的版本，首次安装时为空
+	To      string // 发布地址上的最新版本
+	Updated bool   // 已是最新版本时为 false
+	Delta   bool   // 使用增量包更新
+}
+
+// New 创建 Updater，不校验签名，需要时设置 PublicKey
+func New(feedURL, dir string) *Updater {
+	return &Updater{
+		FeedURL: strings.TrimRight(feedURL, "/"),
+		Dir:     dir,
+		Client:  &http.Client{Timeout: 10 * time.Minute},
+	}
+}
+
+// ConfigDir 返回当前版本的数据目录
+func (u *Updater) ConfigDir() string {
+	return filepath.Join(u.Dir, currentDirName, ConfigDirName)
+}
+
+// Installed 返回当前安装的版本，尚未安装时返回 nil
+func (u *Updater) Installed() (*Manifest, error) {
+	return loadInstalled(filepath.Join(u.Dir, currentDirName))
+}
+
+// Previous 返回可以回滚到的上一个版本，没有时返回 nil
+func (u *Updater) Previous() (*Manifest, error) {
+	return loadInstalled(filepath.Join(u.Dir, previousDirName))
+}
+
+// loadInstalled 读取版本目录中的 manifest.json，目录不存在时返回 nil
+func loadInstalled(versionDir string) (*Manifest, error) {
+	m, err := LoadManifest(filepath.Join(versionDir, ManifestName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取已安装的版本失败: %v", err)
+	}
+	return m, nil
+}
+
+// Latest 返回发布地址上的最新版本
+func (u *Updater) Latest() (*Manifest, error) {
+	_, m, err := u.fetchManifest()
+	return m, err
+}
+
+// Update 更新到发布地址上的最新版本
+// 已安装的版本正好是增量包基于的版本时优先使用增量包，增量包失败时改为下载完整的压缩包；
+// 新版本在暂存目录中解压并按 manifest.json 校验全部文件后才替换当前版本，当前版本保留为 previous
+func (u *Updater) Update() (*Result, error) {
+	installed, err := u.Installed()
+	if err != nil {
+		return nil, err
+	}
+	manifestData, latest, err := u.fetchManifest()
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{To: latest.Version}
+	if installed != nil {
+		result.From = installed.Version
+		if installed.Version == latest.Version {
+			return result, nil
+		}
+	}
+
+	if err := os.MkdirAll(u.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建安装目录失败: %v", err)
+	}
+	unlock, err := u.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	u.removeStaleStaging()
+	stagingDir, err := os.MkdirTemp(u.Dir, stagingPrefix+"*")
+	if err != nil {
+		return nil, fmt.Errorf("创建暂存目录失败: %v", err)
+	}
+	defer os.RemoveAll(stagingDir) // 安装成功后为空操作
+
+	configDir := filepath.Join(stagingDir, ConfigDirName)
+	if installed != nil && latest.Delta != nil && latest.Delta.From == installed.Version {
+		if err := u.stageDelta(configDir, latest); err != nil {
+			u.logf("增量更新失败，改为下载完整的压缩包: %v", err)
+			if err := os.RemoveAll(configDir); err != nil {
+				return nil, fmt.Errorf("清理暂存目录失败: %v", err)
+			}
+		} else {
+			result.Delta = true
+		}
+	}
+	if !result.Delta {
+		if err := u.stageFull(configDir, latest); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(stagingDir, ManifestName), manifestData, 0644); err != nil {
+		return nil, fmt.Errorf("保存 %s 失败: %v", ManifestName, err)
+	}
+	if err := u.install(stagingDir); err != nil {
+		return nil, err
+	}
+	result.Updated = true
+	return result, nil
+}
+
+// Rollback 回滚到上一个版本，当前版本成为新的 previous，再次回滚即可恢复
+func (u *Updater) Rollback() (*Manifest, error) {
+	if _, err := os.Stat(u.Dir); os.IsNotExist(err) {
+		return nil, fmt.Errorf("没有可回滚的版本")
+	}
+	// 先获取锁再读取 previous，避免读取后被同时运行的更新替换
+	unlock, err := u.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	previous, err := u.Previous()
+	if err != nil {
+		return nil, err
+	}
+	if previous == nil {
+		return nil, fmt.Errorf("没有可回滚的版本")
+	}
+
+	currentDir := filepath.Join(u.Dir, currentDirName)
+	previousDir := filepath.Join(u.Dir, previousDirName)
+	swapDir, err := os.MkdirTemp(u.Dir, stagingPrefix+"*")
+	if err != nil {
+		return nil, fmt.Errorf("创建暂存目录失败: %v", err)
+	}
+	defer os.RemoveAll(swapDir)
+
+	swapped := filepath.Join(swapDir, currentDirName)
+	hasCurrent := true
+	if err := os.Rename(currentDir, swapped); os.IsNotExist(err) {
+		hasCurrent = false
+	} else if err != nil {
+		return nil, fmt.Errorf("移动当前版本失败: %v", err)
+	}
+	if err := os.Rename(previousDir, currentDir); err != nil {
+		if hasCurrent {
+			os.Rename(swapped, currentDir)
+		}
+		return nil, fmt.Errorf("恢复上一个版本失败: %v", err)
+	}
+	if hasCurrent {
+		if err := os.Rename(swapped, previousDir); err != nil {
+			return nil, fmt.Errorf("保留当前版本失败: %v", err)
+		}
+	}
+	return previous, nil
+}
+
+// fetchManifest 下载最新的 manifest.json，返回原始内容和解析结果
+func (u *Updater) fetchManifest() ([]byte, *Manifest, error) {
+	data, err := u.downloadVerified(ManifestName)
+	if err != nil {
+		return nil, nil, err
+	}
+	m, err := ParseManifest(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if u.PublicKey != nil && m.SigningKey != Fingerprint(u.PublicKey) {
+		return nil, nil, fmt.Errorf("%s 中的公钥指纹 %s 与使用的公钥 %s 不一致", ManifestName, m.SigningKey, Fingerprint(u.PublicKey))
+	}
+	return data, m, nil
+}
+
+// stageFull 下载完整的压缩包，解压到 configDir 并校验
+func (u *Updater) stageFull(configDir string, latest *Manifest) error {
+	u.logf("下载 %s (%d 字节)", latest.Archive, latest.Size)
+	archive, err := u.downloadVerified(latest.Archive)
+	if err != nil {
+		return err
+	}
+	if err := CheckArchive(latest.Archive, archive, latest.Size, latest.SHA256); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %v", err)
+	}
+	if err := extractArchive(archive, configDir); err != nil {
+		return err
+	}
+	if err := VerifyTree(configDir, latest); err != nil {
+		return fmt.Errorf("版本 %s 的数据校验失败: %v", latest.Version, err)
+	}
+	return nil
+}
+
+// stageDelta 复制当前版本的数据到 configDir，应用增量包并校验
+func (u *Updater) stageDelta(configDir string, latest *Manifest) error {
+	delta := latest.Delta
+	u.logf("下载增量包 %s (%d 字节)", delta.Archive, delta.Size)
+	archive, err := u.downloadVerified(delta.Archive)
+	if err != nil {
+		return err
+	}
+	if err := CopyDir(u.ConfigDir(), configDir); err != nil {
+		return err
+	}
+	_, err = ApplyDelta(configDir, archive, latest)
+	return err
+}
+
+// install 用暂存目录替换当前版本，当前版本移动到 previous，原来的 previous 被删除
+func (u *Updater) install(stagingDir string) error {
+	currentDir := filepath.Join(u.Dir, currentDirName)
+	previousDir := filepath.Join(u.Dir, previousDirName)
+	if err := os.Chmod(stagingDir, 0755); err != nil {
+		return fmt.Errorf("设置目录权限失败: %v", err)
+	}
+	if err := os.RemoveAll(previousDir); err != nil {
+		return fmt.Errorf("删除旧版本失败: %v", err)
+	}
+
+	hasCurrent := true
+	if err := os.Rename(currentDir, previousDir); os.IsNotExist(err) {
+		hasCurrent = false
+	} else if err != nil {
+		return fmt.Errorf("保留当前版本失败: %v", err)
+	}
+	if err := os.Rename(stagingDir, currentDir); err != nil {
+		if hasCurrent {
+			os.Rename(previousDir, currentDir)
+		}
+		return fmt.Errorf("安装新版本失败: %v", err)
+	}
+	return nil
+}
+
+// lock 创建安装目录中的锁文件，避免同时运行的更新 (如定时任务和手动更新) 互相删除暂存目录或替换版本
+// 返回释放锁的函数；超过 staleLockAge 的锁文件视为上次中断时留下的，会被替换
+func (u *Updater) lock() (func(), error) {
+	lockPath := filepath.Join(u.Dir, lockFileName)
+	for attempt := 0; ; attempt++ {
+		file, err := os.OpenFile(lockPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			fmt.Fprintf(file, "%d %s\n", os.Getpid(), time.Now().UTC().Format(time.RFC3339))
+			file.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("创建锁文件失败: %v", err)
+		}
+		info, statErr := os.Stat(lockPath)
+		if attempt > 0 || statErr != nil || time.Since(info.ModTime()) < staleLockAge {
+			return nil, fmt.Errorf("安装目录正在被另一个更新或回滚使用 (锁文件 %s)，确认没有其他更新在运行时可以删除该文件", lockPath)
+		}
+		u.logf("删除 %s 前留下的锁文件 %s", info.ModTime().Format(time.RFC3339), lockPath)
+		os.Remove(lockPath)
+	}
+}
+
+// removeStaleStaging 清理上次中断时留下的暂存目录，只在持有锁时调用
+func (u *Updater) removeStaleStaging() {
+	matches, _ := filepath.Glob(filepath.Join(u.Dir, stagingPrefix+"*"))
+	for _, dir := range matches {
+		os.RemoveAll(dir)
+	}
+}
+
+// downloadVerified 下载发布地址下的文件，设置了公钥时同时下载 .sig 并校验签名
+func (u *Updater) downloadVerified(name string) ([]byte, error) {
+	data, err := u.download(name)
+	if err != nil {
+		return nil, err
+	}
+	if u.PublicKey == nil {
+		return data, nil
+	}
+	signature, err := u.download(name + SignatureExt)
+	if err != nil {
+		return nil, err
+	}
+	if err := VerifySignature(u.PublicKey, data, signature); err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return data, nil
+}
+
+// download 下载发布地址下的文件
+func (u *Updater) download(name string) ([]byte, error) {
+	client := u.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Get(u.FeedURL + "/" + name)
+	if err != nil {
+		return nil, fmt.Errorf("下载 %s 失败: %v", name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("下载 %s 失败: HTTP %d", name, resp.StatusCode)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("下载 %s 失败: %v", name, err)
+	}
+	return data, nil
+}
+
+// CopyDir 复制目录中的全部文件
+func CopyDir(srcDir, destDir string) error {
+	return filepath.WalkDir(srcDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("读取 %s 失败: %v", p, err)
+		}
+		rel, err := filepath.Rel(srcDir, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(destDir, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		file, err := os.Open(p)
+		if err != nil {
+			return fmt.Errorf("读取 %s 失败: %v", p, err)
+		}
+		defer file.Close()
+		return writeFile(target, file)
+	})
+}
+
+func (u *Updater) logf(format string, a ...interface{}) {
+	if u.Logf != nil {
+		u.Logf(format, a...)
+	}
+}
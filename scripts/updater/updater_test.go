@@ -0,0 +1,460 @@
+package updater
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testFeed 本地的发布地址，内容可以在测试中替换
+type testFeed struct {
+	mu    sync.Mutex
+	files map[string][]byte
+	priv  ed25519.PrivateKey
+	pub   ed25519.PublicKey
+}
+
+func newTestFeed(t *testing.T) (*testFeed, *httptest.Server) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed := &testFeed{files: map[string][]byte{}, priv: priv, pub: pub}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		feed.mu.Lock()
+		data, ok := feed.files[strings.TrimPrefix(r.URL.Path, "/")]
+		feed.mu.Unlock()
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	t.Cleanup(server.Close)
+	return feed, server
+}
+
+// put 发布文件并写入签名
+func (f *testFeed) put(name string, data []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.files[name] = data
+	f.files[name+SignatureExt] = []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(f.priv, data)) + "\n")
+}
+
+// writeTestArchive 生成与 tmdb-manager pack 结构相同的压缩包
+func writeTestArchive(t *testing.T, files map[string]string, rootFiles map[string][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	write := func(name string, data []byte) {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(data))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, relPath := range sortedKeys(files) {
+		write(path.Join(RootDir, ConfigDirName, relPath), []byte(files[relPath]))
+	}
+	for name, data := range rootFiles {
+		write(path.Join(RootDir, name), data)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// publish 发布一个版本；base 不为 nil 时同时发布相对 base 的增量包
+func (f *testFeed) publish(t *testing.T, version string, files map[string]string, base *Manifest) *Manifest {
+	t.Helper()
+	archive := writeTestArchive(t, files, nil)
+	m := &Manifest{
+		Version:    version,
+		SigningKey: Fingerprint(f.pub),
+		Archive:    "tmdb_config.tar.gz",
+		Size:       int64(len(archive)),
+		SHA256:     sha256Hex(archive),
+		Files:      len(files),
+	}
+	entries := map[string]*Entry{}
+	for _, relPath := range sortedKeys(files) {
+		parts := strings.SplitN(relPath, "/", 3)
+		key := parts[0] + "/" + parts[1]
+		if entries[key] == nil {
+			entries[key] = &Entry{Type: parts[0], ID: parts[1]}
+		}
+		entries[key].Files = append(entries[key].Files, File{Path: parts[2], Size: int64(len(files[relPath])), SHA256: sha256Hex([]byte(files[relPath]))})
+	}
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		m.Entries = append(m.Entries, *entries[key])
+	}
+
+	if base != nil {
+		previous := base.FileHashes()
+		index := DeltaIndex{From: base.Version, To: version, Changed: []string{}, Deleted: []string{}}
+		changed := map[string]string{}
+		for relPath, content := range files {
+			if previous[relPath] != sha256Hex([]byte(content)) {
+				changed[relPath] = content
+				index.Changed = append(index.Changed, relPath)
+			}
+		}
+		for relPath := range previous {
+			if _, ok := files[relPath]; !ok {
+				index.Deleted = append(index.Deleted, relPath)
+			}
+		}
+		indexData, err := json.Marshal(index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		delta := writeTestArchive(t, changed, map[string][]byte{DeltaIndexName: indexData})
+		m.Delta = &Delta{Archive: "tmdb_config.delta.tar.gz", From: base.Version, Size: int64(len(delta)), SHA256: sha256Hex(delta), Changed: len(index.Changed), Deleted: len(index.Deleted)}
+		f.put(m.Delta.Archive, delta)
+	}
+
+	f.put(m.Archive, archive)
+	f.publishManifest(t, m)
+	return m
+}
+
+// publishManifest 发布 manifest.json
+func (f *testFeed) publishManifest(t *testing.T, m *Manifest) {
+	t.Helper()
+	data, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.put(ManifestName, data)
+}
+
+var (
+	v1Files = map[string]string{
+		"movie/1/details.json": `{"id": 1, "title": "A"}`,
+		"movie/1/credits.json": `{"cast": []}`,
+		"tv/2/details.json":    `{"id": 2, "name": "B"}`,
+	}
+	v2Files = map[string]string{
+		"movie/1/details.json": `{"id": 1, "title": "A2"}`,
+		"movie/1/credits.json": `{"cast": []}`,
+		"movie/3/details.json": `{"id": 3, "title": "C"}`,
+	}
+)
+
+// newTestUpdater 创建校验签名的 Updater
+func newTestUpdater(t *testing.T, feed *testFeed, server *httptest.Server) *Updater {
+	u := New(server.URL, t.TempDir())
+	u.PublicKey = feed.pub
+	return u
+}
+
+// assertInstalled 检查当前版本的版本号和数据
+func assertInstalled(t *testing.T, u *Updater, version string, files map[string]string) {
+	t.Helper()
+	installed, err := u.Installed()
+	if err != nil || installed == nil || installed.Version != version {
+		t.Fatalf("已安装的版本 = %+v, %v, 期望 %s", installed, err, version)
+	}
+	var got []string
+	filepath.WalkDir(u.ConfigDir(), func(p string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			rel, _ := filepath.Rel(u.ConfigDir(), p)
+			got = append(got, filepath.ToSlash(rel))
+			if data, _ := os.ReadFile(p); string(data) != files[filepath.ToSlash(rel)] {
+				t.Errorf("%s = %s, 期望 %s", rel, data, files[filepath.ToSlash(rel)])
+			}
+		}
+		return nil
+	})
+	if want := sortedKeys(files); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("已安装的文件 = %v, 期望 %v", got, want)
+	}
+}
+
+func TestUpdateFullInstall(t *testing.T) {
+	feed, server := newTestFeed(t)
+	feed.publish(t, "v1", v1Files, nil)
+	u := newTestUpdater(t, feed, server)
+
+	result, err := u.Update()
+	if err != nil {
+		t.Fatalf("Update() 失败: %v", err)
+	}
+	if *result != (Result{To: "v1", Updated: true}) {
+		t.Errorf("Update() = %+v", result)
+	}
+	assertInstalled(t, u, "v1", v1Files)
+
+	// 已是最新版本
+	if result, err = u.Update(); err != nil || result.Updated {
+		t.Errorf("再次 Update() = %+v, %v, 期望无需更新", result, err)
+	}
+}
+
+func TestUpdateDelta(t *testing.T) {
+	feed, server := newTestFeed(t)
+	v1 := feed.publish(t, "v1", v1Files, nil)
+	u := newTestUpdater(t, feed, server)
+	if _, err := u.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	feed.publish(t, "v2", v2Files, v1)
+	// 增量包可用时不应下载完整的压缩包
+	feed.mu.Lock()
+	delete(feed.files, "tmdb_config.tar.gz")
+	feed.mu.Unlock()
+
+	result, err := u.Update()
+	if err != nil {
+		t.Fatalf("Update() 失败: %v", err)
+	}
+	if *result != (Result{From: "v1", To: "v2", Updated: true, Delta: true}) {
+		t.Errorf("Update() = %+v", result)
+	}
+	assertInstalled(t, u, "v2", v2Files)
+	if _, err := os.Stat(filepath.Join(u.ConfigDir(), "tv")); !os.IsNotExist(err) {
+		t.Error("删除文件后为空的目录应一并删除")
+	}
+}
+
+func TestUpdateFallsBackToFullArchive(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(t *testing.T, feed *testFeed, u *Updater, v1 *Manifest)
+	}{
+		{
+			name: "增量包基于其他版本",
+			prepare: func(t *testing.T, feed *testFeed, u *Updater, v1 *Manifest) {
+				other := *v1
+				other.Version = "v0"
+				feed.publish(t, "v2", v2Files, &other)
+			},
+		},
+		{
+			name: "本地数据与增量包基于的版本不一致",
+			prepare: func(t *testing.T, feed *testFeed, u *Updater, v1 *Manifest) {
+				feed.publish(t, "v2", v2Files, v1)
+				if err := os.WriteFile(filepath.Join(u.ConfigDir(), "movie", "1", "credits.json"), []byte(`{"cast": [{"id": 9}]}`), 0644); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, server := newTestFeed(t)
+			v1 := feed.publish(t, "v1", v1Files, nil)
+			u := newTestUpdater(t, feed, server)
+			if _, err := u.Update(); err != nil {
+				t.Fatal(err)
+			}
+			tt.prepare(t, feed, u, v1)
+
+			result, err := u.Update()
+			if err != nil {
+				t.Fatalf("Update() 失败: %v", err)
+			}
+			if !result.Updated || result.Delta {
+				t.Errorf("Update() = %+v, 期望使用完整的压缩包", result)
+			}
+			assertInstalled(t, u, "v2", v2Files)
+		})
+	}
+}
+
+func TestUpdateRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		tamper  func(t *testing.T, feed *testFeed, m *Manifest)
+		wantErr string
+	}{
+		{
+			name: "压缩包的 SHA256 与 manifest.json 不一致",
+			tamper: func(t *testing.T, feed *testFeed, m *Manifest) {
+				m.SHA256 = sha256Hex([]byte("other"))
+				feed.publishManifest(t, m)
+			},
+			wantErr: "SHA256",
+		},
+		{
+			name: "压缩包的签名无效",
+			tamper: func(t *testing.T, feed *testFeed, m *Manifest) {
+				feed.mu.Lock()
+				feed.files[m.Archive+SignatureExt] = feed.files[ManifestName+SignatureExt]
+				feed.mu.Unlock()
+			},
+			wantErr: "签名无效",
+		},
+		{
+			name: "manifest.json 的签名无效",
+			tamper: func(t *testing.T, feed *testFeed, m *Manifest) {
+				feed.mu.Lock()
+				feed.files[ManifestName] = bytes.Replace(feed.files[ManifestName], []byte(`"v2"`), []byte(`"v9"`), 1)
+				feed.mu.Unlock()
+			},
+			wantErr: "签名无效",
+		},
+		{
+			name: "压缩包中的数据与 manifest.json 不一致",
+			tamper: func(t *testing.T, feed *testFeed, m *Manifest) {
+				archive := writeTestArchive(t, map[string]string{"movie/1/details.json": `{}`}, nil)
+				m.Size, m.SHA256 = int64(len(archive)), sha256Hex(archive)
+				feed.put(m.Archive, archive)
+				feed.publishManifest(t, m)
+			},
+			wantErr: "数据校验失败",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, server := newTestFeed(t)
+			feed.publish(t, "v1", v1Files, nil)
+			u := newTestUpdater(t, feed, server)
+			if _, err := u.Update(); err != nil {
+				t.Fatal(err)
+			}
+			tt.tamper(t, feed, feed.publish(t, "v2", v2Files, nil))
+
+			if _, err := u.Update(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Update() 错误 = %v, 期望包含 %q", err, tt.wantErr)
+			}
+			assertInstalled(t, u, "v1", v1Files)
+			if matches, _ := filepath.Glob(filepath.Join(u.Dir, stagingPrefix+"*")); len(matches) > 0 {
+				t.Errorf("留下了暂存目录: %v", matches)
+			}
+		})
+	}
+}
+
+func TestRollback(t *testing.T) {
+	feed, server := newTestFeed(t)
+	v1 := feed.publish(t, "v1", v1Files, nil)
+	u := newTestUpdater(t, feed, server)
+	if _, err := u.Rollback(); err == nil {
+		t.Error("未安装时 Rollback() 应失败")
+	}
+	if _, err := u.Update(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := u.Rollback(); err == nil {
+		t.Error("没有上一个版本时 Rollback() 应失败")
+	}
+	feed.publish(t, "v2", v2Files, v1)
+	if _, err := u.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := u.Rollback()
+	if err != nil || restored.Version != "v1" {
+		t.Fatalf("Rollback() = %+v, %v, 期望 v1", restored, err)
+	}
+	assertInstalled(t, u, "v1", v1Files)
+	if previous, err := u.Previous(); err != nil || previous.Version != "v2" {
+		t.Errorf("Previous() = %+v, %v, 期望 v2", previous, err)
+	}
+
+	// 再次回滚恢复 v2
+	if _, err := u.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	assertInstalled(t, u, "v2", v2Files)
+}
+
+func TestLockHeld(t *testing.T) {
+	feed, server := newTestFeed(t)
+	v1 := feed.publish(t, "v1", v1Files, nil)
+	u := newTestUpdater(t, feed, server)
+	if _, err := u.Update(); err != nil {
+		t.Fatal(err)
+	}
+	feed.publish(t, "v2", v2Files, v1)
+
+	unlock, err := u.lock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := u.Update(); err == nil || !strings.Contains(err.Error(), "锁文件") {
+		t.Errorf("持有锁时 Update() 错误 = %v, 期望提示锁文件", err)
+	}
+	if _, err := u.Rollback(); err == nil || !strings.Contains(err.Error(), "锁文件") {
+		t.Errorf("持有锁时 Rollback() 错误 = %v, 期望提示锁文件", err)
+	}
+	assertInstalled(t, u, "v1", v1Files)
+
+	unlock()
+	if _, err := u.Update(); err != nil {
+		t.Errorf("释放锁后 Update() 失败: %v", err)
+	}
+	assertInstalled(t, u, "v2", v2Files)
+}
+
+func TestReadArchiveRejectsUnsafePaths(t *testing.T) {
+	for _, name := range []string{
+		RootDir + "/tmdb_config/../../evil.json",
+		"../evil.json",
+		"/etc/evil.json",
+		"other/tmdb_config/movie/1/details.json",
+	} {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(gz)
+		data := []byte(`{}`)
+		if err := tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(data))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			t.Fatal(err)
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := gz.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := ReadArchive(buf.Bytes()); err == nil || !strings.Contains(err.Error(), "路径不正确") {
+			t.Errorf("%s: 错误 = %v, 期望拒绝该路径", name, err)
+		}
+	}
+}
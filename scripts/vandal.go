@@ -0,0 +1,341 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"tmdb-manager/models"
+)
+
+// suspicion 疑似被恶意编辑的内容
+type suspicion struct {
+	file   string // 相对于条目目录的路径
+	field  string // 字段，如 title、overview
+	value  string
+	reason string
+}
+
+// 标题/简介中常见的广告、引流用语
+var adKeywords = []string{
+	"微信", "威信", "薇信", "vx", "qq群", "加群", "群号", "公众号", "扫码", "二维码",
+	"免费观看", "在线观看", "高清下载", "网盘", "资源站", "百度云", "迅雷下载",
+	"博彩", "棋牌", "娱乐城", "代理加盟",
+	"telegram", "t.me",
+}
+
+// 常见的辱骂用语
+var profanityKeywords = []string{
+	"傻逼", "傻b", "煞笔", "沙比", "sb电影", "垃圾电影", "垃圾剧", "狗屎", "死全家", "操你", "草你",
+	"fuck", "shit", "bitch",
+}
+
+// 关键词的匹配规则，英文关键词按单词匹配，避免 "shit" 匹配到 "shitake" 这样的正常单词
+var (
+	adPattern        = keywordPattern(adKeywords)
+	profanityPattern = keywordPattern(profanityKeywords)
+)
+
+var (
+	urlPattern     = regexp.MustCompile(`(?i)(https?://|www\.|\b[a-z0-9-]+\.(com|net|org|cn|cc|top|xyz|vip)\b)`)
+	contactPattern = regexp.MustCompile(`\d{6,}`) // QQ号、手机号等长数字
+)
+
+// 中文标题中正常出现的文字之外的书写系统，只在原始标题中也出现时才认为正常
+var unusualScripts = map[string]*unicode.RangeTable{
+	"西里尔字母": unicode.Cyrillic,
+	"阿拉伯字母": unicode.Arabic,
+	"希伯来字母": unicode.Hebrew,
+	"泰文":    unicode.Thai,
+	"天城文":   unicode.Devanagari,
+	"希腊字母":  unicode.Greek,
+	"谚文":    unicode.Hangul,
+	"平假名":   unicode.Hiragana,
+	"片假名":   unicode.Katakana,
+}
+
+// 简介长度与参考语言相差超过这个倍数时提示
+const overviewLengthRatio = 10
+
+// detectVandalism 检查获取到的数据中疑似被恶意编辑的标题和简介
+func detectVandalism(mediaType string, files mediaFiles) []suspicion {
+	var found []suspicion
+
+	var originalTitle string
+	if details, ok := files["details.json"]; ok {
+		var detailsFound []suspicion
+		detailsFound, originalTitle = checkDetails(mediaType, details)
+		found = append(found, detailsFound...)
+	}
+
+	// 季/集只检查文本本身
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		if p != "details.json" && path.Base(p) == "details.json" {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		var season models.Season
+		if err := models.FromMap(files[p], &season); err != nil {
+			continue
+		}
+		found = append(found, checkText(p, "name", season.Name, originalTitle)...)
+		found = append(found, checkText(p, "overview", season.Overview, originalTitle)...)
+	}
+	return found
+}
+
+// checkDetails 检查电影/电视剧详情，同时返回原始标题供检查季/集时使用
+func checkDetails(mediaType string, data map[string]interface{}) ([]suspicion, string) {
+	const file = "details.json"
+	var title, originalTitle, overview, titleField string
+	var translations *models.Translations
+	var alternatives []models.AlternativeTitle
+
+	if mediaType == "movie" {
+		var movie models.MovieDetails
+		if err := models.FromMap(data, &movie); err != nil {
+			return nil, ""
+		}
+		title, originalTitle, overview, titleField = movie.Title, movie.OriginalTitle, movie.Overview, "title"
+		translations = movie.Translations
+		if movie.AlternativeTitles != nil {
+			alternatives = movie.AlternativeTitles.List()
+		}
+	} else {
+		var tv models.TVDetails
+		if err := models.FromMap(data, &tv); err != nil {
+			return nil, ""
+		}
+		title, originalTitle, overview, titleField = tv.Name, tv.OriginalName, tv.Overview, "name"
+		translations = tv.Translations
+		if tv.AlternativeTitles != nil {
+			alternatives = tv.AlternativeTitles.List()
+		}
+	}
+
+	var found []suspicion
+	found = append(found, checkText(file, titleField, title, originalTitle)...)
+	found = append(found, checkText(file, "overview", overview, originalTitle)...)
+
+	// 标题应能在中文的原始标题、其他中文地区的翻译或中文地区的别名中找到，没有任何参考标题时无法判断
+	if title != "" && hasSimplifiedTitle(translations) {
+		if refs := titleReferences(originalTitle, translations, alternatives); len(refs) > 0 && !titleKnown(title, refs) {
+			found = append(found, suspicion{file: file, field: titleField, value: title,
+				reason: "与原始标题、其他中文地区的翻译和中文别名都不一致"})
+		}
+	}
+
+	if strings.TrimSpace(overview) == "" {
+		found = append(found, suspicion{file: file, field: "overview", value: overview, reason: "简介为空"})
+	} else if refLen, count := referenceOverviewLength(translations); count > 0 {
+		zhLen := len([]rune(overview))
+		switch {
+		case refLen >= 200 && zhLen*overviewLengthRatio < refLen:
+			found = append(found, suspicion{file: file, field: "overview", value: overview,
+				reason: fmt.Sprintf("明显短于其他 %d 种语言的简介 (%d 字 / 中位数 %d 字)", count, zhLen, refLen)})
+		case refLen >= 50 && zhLen > refLen*overviewLengthRatio/3:
+			found = append(found, suspicion{file: file, field: "overview", value: overview,
+				reason: fmt.Sprintf("明显长于其他 %d 种语言的简介 (%d 字 / 中位数 %d 字)", count, zhLen, refLen)})
+		}
+	}
+	return found, originalTitle
+}
+
+// checkText 检查文本中的链接、广告、辱骂用语和异常文字
+// original 为原始标题，其中出现的书写系统不算异常
+func checkText(file, field, value, original string) []suspicion {
+	if value == "" {
+		return nil
+	}
+	var found []suspicion
+	add := func(reason string) {
+		found = append(found, suspicion{file: file, field: field, value: value, reason: reason})
+	}
+
+	if urlPattern.MatchString(value) {
+		add("包含网址")
+	}
+	// 简介中可能出现金额等长数字，只检查标题
+	if field != "overview" && contactPattern.MatchString(value) && !contactPattern.MatchString(original) {
+		add("包含疑似QQ号/手机号的长数字")
+	}
+	if match := adPattern.FindString(value); match != "" {
+		add(fmt.Sprintf("包含广告用语 \"%s\"", strings.ToLower(match)))
+	}
+	if match := profanityPattern.FindString(value); match != "" {
+		add(fmt.Sprintf("包含辱骂用语 \"%s\"", strings.ToLower(match)))
+	}
+
+	for _, name := range sortedScriptNames() {
+		table := unusualScripts[name]
+		if containsScript(value, table) && !containsScript(original, table) {
+			add("包含" + name)
+		}
+	}
+	for _, r := range value {
+		if strings.ContainsRune(original, r) {
+			continue
+		}
+		if unicode.Is(unicode.So, r) || unicode.Is(unicode.Co, r) || (unicode.IsControl(r) && r != '\n' && r != '\r' && r != '\t') {
+			add("包含表情符号或控制字符")
+			break
+		}
+	}
+	return found
+}
+
+// keywordPattern 生成匹配任一关键词的正则表达式，不区分大小写
+// 中文没有单词边界，按子串匹配；以英文字母或数字开头/结尾的关键词在该侧要求单词边界，结尾允许常见的词形变化
+func keywordPattern(keywords []string) *regexp.Regexp {
+	parts := make([]string, len(keywords))
+	for i, keyword := range keywords {
+		part := regexp.QuoteMeta(keyword)
+		if isASCIIWordByte(keyword[0]) {
+			part = `\b` + part
+		}
+		if isASCIIWordByte(keyword[len(keyword)-1]) {
+			part += `(?:s|es|ed|ing)?\b`
+		}
+		parts[i] = part
+	}
+	return regexp.MustCompile(`(?i)(?:` + strings.Join(parts, "|") + `)`)
+}
+
+// isASCIIWordByte 判断是否为正则表达式 \b 所识别的单词字符
+func isASCIIWordByte(c byte) bool {
+	return c == '_' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func sortedScriptNames() []string {
+	names := make([]string, 0, len(unusualScripts))
+	for name := range unusualScripts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func containsScript(s string, table *unicode.RangeTable) bool {
+	for _, r := range s {
+		if unicode.Is(table, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasSimplifiedTitle 判断是否有 zh-CN 翻译的标题
+// 没有时TMDB返回的是默认语言的标题，不是中文标题，不做比较
+func hasSimplifiedTitle(translations *models.Translations) bool {
+	if translations == nil {
+		return false
+	}
+	for _, t := range translations.Translations {
+		if t.ISO639_1 == "zh" && t.ISO3166_1 == "CN" && (t.Data.Title != "" || t.Data.Name != "") {
+			return true
+		}
+	}
+	return false
+}
+
+// titleReferences 返回可用于核对中文标题的参考标题: 中文的原始标题、其他中文地区的翻译和中文地区的别名
+// 获取的标题就来自 zh-CN 翻译，与它比较总是一致，因此不使用 zh-CN 翻译
+func titleReferences(originalTitle string, translations *models.Translations, alternatives []models.AlternativeTitle) []string {
+	var refs []string
+	if containsScript(originalTitle, unicode.Han) {
+		refs = append(refs, originalTitle)
+	}
+	if translations != nil {
+		for _, t := range translations.Translations {
+			if t.ISO639_1 == "zh" && t.ISO3166_1 != "CN" && chineseRegions[t.ISO3166_1] {
+				refs = append(refs, t.Data.Title, t.Data.Name)
+			}
+		}
+	}
+	for _, alt := range alternatives {
+		if chineseRegions[alt.ISO3166_1] {
+			refs = append(refs, alt.Title)
+		}
+	}
+
+	nonEmpty := refs[:0]
+	for _, ref := range refs {
+		if normalizeTitle(ref) != "" {
+			nonEmpty = append(nonEmpty, ref)
+		}
+	}
+	return nonEmpty
+}
+
+// titleKnown 判断标题是否与任一参考标题一致
+func titleKnown(title string, refs []string) bool {
+	key := normalizeTitle(title)
+	for _, ref := range refs {
+		if key == normalizeTitle(ref) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeTitle 忽略大小写、空白、标点和全角/半角差异
+func normalizeTitle(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= 0xFF01 && r <= 0xFF5E {
+			r -= 0xFEE0
+		}
+		if unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r) {
+			continue
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// referenceOverviewLength 返回其他语言简介字数的中位数和参与比较的语言数
+// 只与一种语言比较时，该语言的简介本身过短或过长也会导致误报
+func referenceOverviewLength(translations *models.Translations) (int, int) {
+	if translations == nil {
+		return 0, 0
+	}
+	var lengths []int
+	for _, t := range translations.Translations {
+		if t.ISO639_1 == "zh" || strings.TrimSpace(t.Data.Overview) == "" {
+			continue
+		}
+		lengths = append(lengths, len([]rune(t.Data.Overview)))
+	}
+	if len(lengths) == 0 {
+		return 0, 0
+	}
+	sort.Ints(lengths)
+	return lengths[len(lengths)/2], len(lengths)
+}
+
+// printSuspicions 打印疑似被恶意编辑的内容
+func printSuspicions(mediaType, mediaID string, found []suspicion) {
+	if len(found) == 0 {
+		return
+	}
+	fmt.Printf("\n⚠️  %s/%s 的TMDB数据中有 %d 处疑似被恶意编辑的内容，请核对后修正:\n", mediaType, mediaID, len(found))
+	for _, s := range found {
+		fmt.Printf("  %s %s: %s\n", s.file, s.field, s.reason)
+		fmt.Printf("    %s\n", truncateRunes(s.value, 80))
+	}
+}
+
+// truncateRunes 截断过长的文本并去掉换行
+func truncateRunes(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n]) + "..."
+	}
+	return s
+}
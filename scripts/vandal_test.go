@@ -0,0 +1,138 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// 测试用的简介，长度接近正常的中文和英文简介
+var (
+	zhOverview = "在不远的将来，太阳即将毁灭，人类在地球表面建造出巨大的推进器，寻找新的家园。然而宇宙之路危机四伏，为了拯救地球，流浪地球时代的年轻人再次挺身而出，展开争分夺秒的生死之战。"
+	enOverview = strings.Repeat("So what if the sun is about to burn out and humanity must move the Earth. ", 3)
+)
+
+// testTranslation 生成 translations 中的一个翻译
+func testTranslation(language, region, title, overview string) map[string]interface{} {
+	return map[string]interface{}{
+		"iso_639_1":    language,
+		"iso_3166_1":   region,
+		"english_name": language,
+		"data":         map[string]interface{}{"title": title, "overview": overview},
+	}
+}
+
+// testMovie 生成电影的 details.json，zh-CN 翻译与返回的标题和简介一致
+func testMovie(title, overview string, translations ...map[string]interface{}) map[string]interface{} {
+	list := []interface{}{testTranslation("zh", "CN", title, overview), testTranslation("en", "US", "The Wandering Earth II", enOverview)}
+	for _, t := range translations {
+		list = append(list, t)
+	}
+	return map[string]interface{}{
+		"id":                 842675,
+		"title":              title,
+		"original_title":     "流浪地球2",
+		"overview":           overview,
+		"translations":       map[string]interface{}{"translations": list},
+		"alternative_titles": map[string]interface{}{"titles": []interface{}{map[string]interface{}{"iso_3166_1": "CN", "title": "流浪地球Ⅱ"}}},
+	}
+}
+
+func TestDetectVandalism(t *testing.T) {
+	tests := []struct {
+		name  string
+		files mediaFiles
+		want  []string // 文件 字段: 原因
+	}{
+		{
+			name:  "正常的数据",
+			files: mediaFiles{"details.json": testMovie("流浪地球2", zhOverview)},
+		},
+		{
+			name:  "与中文别名一致的标题",
+			files: mediaFiles{"details.json": testMovie("流浪地球 Ⅱ", zhOverview)},
+		},
+		{
+			name:  "被篡改的标题",
+			files: mediaFiles{"details.json": testMovie("史上最烂电影", zhOverview)},
+			want:  []string{"details.json title: 与原始标题、其他中文地区的翻译和中文别名都不一致"},
+		},
+		{
+			name: "没有 zh-CN 翻译时返回的是默认语言的标题，不比较",
+			files: mediaFiles{"details.json": func() map[string]interface{} {
+				details := testMovie("The Wandering Earth II", zhOverview)
+				details["translations"] = map[string]interface{}{"translations": []interface{}{testTranslation("en", "US", "The Wandering Earth II", enOverview)}}
+				return details
+			}()},
+		},
+		{
+			name:  "简介中的网址",
+			files: mediaFiles{"details.json": testMovie("流浪地球2", zhOverview+"更多资源请访问 www.example.com")},
+			want:  []string{"details.json overview: 包含网址"},
+		},
+		{
+			name:  "简介中的广告",
+			files: mediaFiles{"details.json": testMovie("流浪地球2", zhOverview+"加微信看全集")},
+			want:  []string{"details.json overview: 包含广告用语 \"微信\""},
+		},
+		{
+			name:  "标题中的长数字",
+			files: mediaFiles{"details.json": testMovie("流浪地球2", zhOverview), "season/1/details.json": {"name": "第一季 QQ 12345678"}},
+			want:  []string{"season/1/details.json name: 包含疑似QQ号/手机号的长数字"},
+		},
+		{
+			name:  "辱骂用语",
+			files: mediaFiles{"details.json": testMovie("流浪地球2", zhOverview+"Fucking boring.")},
+			want:  []string{"details.json overview: 包含辱骂用语 \"fucking\""},
+		},
+		{
+			name:  "英文关键词按单词匹配",
+			files: mediaFiles{"details.json": testMovie("流浪地球2", zhOverview+"Shitake mushrooms.")},
+		},
+		{
+			name: "原始标题中没有的书写系统",
+			files: mediaFiles{"details.json": testMovie("Земля流浪地球2", zhOverview,
+				testTranslation("zh", "TW", "Земля流浪地球2", ""))},
+			want: []string{"details.json title: 包含西里尔字母"},
+		},
+		{
+			name:  "表情符号",
+			files: mediaFiles{"details.json": testMovie("流浪地球2", zhOverview+"😀")},
+			want:  []string{"details.json overview: 包含表情符号或控制字符"},
+		},
+		{
+			name:  "简介为空",
+			files: mediaFiles{"details.json": testMovie("流浪地球2", " ")},
+			want:  []string{"details.json overview: 简介为空"},
+		},
+		{
+			name:  "简介明显短于其他语言",
+			files: mediaFiles{"details.json": testMovie("流浪地球2", "好看")},
+			want:  []string{"details.json overview: 明显短于其他 1 种语言的简介 (2 字 / 中位数 222 字)"},
+		},
+		{
+			name:  "简介明显长于其他语言",
+			files: mediaFiles{"details.json": testMovie("流浪地球2", strings.Repeat(zhOverview, 9))},
+			want:  []string{"details.json overview: 明显长于其他 1 种语言的简介 (765 字 / 中位数 222 字)"},
+		},
+		{
+			name: "使用其他语言长度的中位数，不受单一语言的影响",
+			files: mediaFiles{"details.json": testMovie("流浪地球2", zhOverview,
+				testTranslation("fr", "FR", "", "Court."),
+				testTranslation("de", "DE", "", "Kurz."),
+			)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, s := range detectVandalism("movie", tt.files) {
+				got = append(got, s.file+" "+s.field+": "+s.reason)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("detectVandalism() = %q, 期望 %q", got, tt.want)
+			}
+		})
+	}
+}
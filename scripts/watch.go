@@ -0,0 +1,341 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// defaultWatchStatePath watch 的状态文件，记录每个条目上次检查的时间，相对于 scripts/cli 目录
+const defaultWatchStatePath = "../cli/watch_state.json"
+
+// 首次检查的条目默认回溯的天数
+const defaultWatchDays = 14
+
+// watchWindow TMDB changes 接口单次请求允许的最长时间范围
+const watchWindow = 14 * 24 * time.Hour
+
+// watchedChangeKeys 需要提醒维护者核对的字段
+var watchedChangeKeys = map[string]bool{
+	"title":              true,
+	"name":               true,
+	"original_title":     true,
+	"original_name":      true,
+	"overview":           true,
+	"tagline":            true,
+	"translations":       true,
+	"alternative_titles": true,
+}
+
+// chineseRegions 使用中文标题的国家/地区
+var chineseRegions = map[string]bool{"CN": true, "TW": true, "HK": true, "MO": true, "SG": true}
+
+// watchState 状态文件内容
+type watchState struct {
+	Titles map[string]string `json:"titles"` // "tv/95480" -> 上次检查的时间 (RFC3339 UTC)
+}
+
+// mediaKey 一个条目的媒体类型和ID
+type mediaKey struct {
+	mediaType string
+	mediaID   string
+}
+
+func (k mediaKey) String() string {
+	return k.mediaType + "/" + k.mediaID
+}
+
+// upstreamChange TMDB changes 接口返回的一条修改记录
+type upstreamChange struct {
+	key      string
+	action   string // added、updated、deleted
+	time     string // 如 2024-03-01 08:00:00 UTC
+	language string // 语言或地区代码，原始标题等与语言无关的字段为空
+	value    interface{}
+	original interface{}
+}
+
+// watchReport 一个条目在检查期间的修改
+type watchReport struct {
+	media   mediaKey
+	title   string
+	since   time.Time
+	changes []upstreamChange
+}
+
+// listMediaKeys 列出数据目录下的全部条目，按类型和ID排序
+func listMediaKeys(root string) ([]mediaKey, error) {
+	if !checkDirectoryExists(root) {
+		return nil, fmt.Errorf("目录不存在: %s", root)
+	}
+	var keys []mediaKey
+	for _, mediaType := range []string{"movie", "tv"} {
+		entries, err := os.ReadDir(filepath.Join(root, mediaType))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("读取 %s 失败: %v", mediaType, err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() && isNumericID(entry.Name()) {
+				keys = append(keys, mediaKey{mediaType: mediaType, mediaID: entry.Name()})
+			}
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].mediaType != keys[j].mediaType {
+			return keys[i].mediaType < keys[j].mediaType
+		}
+		if len(keys[i].mediaID) != len(keys[j].mediaID) {
+			return len(keys[i].mediaID) < len(keys[j].mediaID)
+		}
+		return keys[i].mediaID < keys[j].mediaID
+	})
+	return keys, nil
+}
+
+// loadWatchState 读取状态文件，文件不存在时返回空状态
+func loadWatchState(statePath string) (*watchState, error) {
+	state := &watchState{Titles: make(map[string]string)}
+	data, err := os.ReadFile(statePath)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取状态文件失败: %v", err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("状态文件格式错误: %v", err)
+	}
+	if state.Titles == nil {
+		state.Titles = make(map[string]string)
+	}
+	return state, nil
+}
+
+// fetchChanges 获取条目在 [start, end) 期间的修改记录，超过14天时分段请求
+func (f *TMDBFetcher) fetchChanges(key mediaKey, start, end time.Time) ([]upstreamChange, error) {
+	endpoint := fmt.Sprintf("/%s/%s/changes", key.mediaType, key.mediaID)
+	var changes []upstreamChange
+	for windowStart := start; windowStart.Before(end); windowStart = windowStart.Add(watchWindow) {
+		windowEnd := windowStart.Add(watchWindow)
+		if windowEnd.After(end) {
+			windowEnd = end
+		}
+		// 接口按天过滤，两端都包含
+		data, err := f.makeRequest(endpoint, map[string]string{
+			"start_date": windowStart.Format("2006-01-02"),
+			"end_date":   windowEnd.Format("2006-01-02"),
+		})
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, parseChanges(data, start, end)...)
+	}
+
+	// 相邻时间段的边界日期会被请求两次
+	seen := make(map[string]bool)
+	unique := changes[:0]
+	for _, c := range changes {
+		id := strings.Join([]string{c.key, c.time, c.action, c.language, formatValue(c.value)}, "\x00")
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, c)
+		}
+	}
+	sort.SliceStable(unique, func(i, j int) bool { return unique[i].time < unique[j].time })
+	return unique, nil
+}
+
+// parseChanges 取出 changes 响应中需要关注的字段在 [start, end) 期间的修改
+func parseChanges(data map[string]interface{}, start, end time.Time) []upstreamChange {
+	var changes []upstreamChange
+	groups, _ := data["changes"].([]interface{})
+	for _, g := range groups {
+		group, ok := g.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		key, _ := group["key"].(string)
+		if !watchedChangeKeys[key] {
+			continue
+		}
+		items, _ := group["items"].([]interface{})
+		for _, it := range items {
+			item, ok := it.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			c := upstreamChange{key: key, value: item["value"], original: item["original_value"]}
+			c.action, _ = item["action"].(string)
+			c.time, _ = item["time"].(string)
+			if t, err := time.Parse("2006-01-02 15:04:05 MST", c.time); err == nil && (t.Before(start) || !t.Before(end)) {
+				continue
+			}
+			c.language = changeLanguage(item)
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+// changeLanguage 返回修改记录的语言代码 (如 zh-CN)，别名返回地区代码
+func changeLanguage(item map[string]interface{}) string {
+	lang, _ := item["iso_639_1"].(string)
+	region, _ := item["iso_3166_1"].(string)
+	if lang == "" {
+		// 翻译的语言在值中
+		for _, v := range []interface{}{item["value"], item["original_value"]} {
+			if m, ok := v.(map[string]interface{}); ok {
+				lang, _ = m["iso_639_1"].(string)
+				if region == "" {
+					region, _ = m["iso_3166_1"].(string)
+				}
+				if lang != "" {
+					break
+				}
+			}
+		}
+	}
+	switch {
+	case lang != "" && region != "":
+		return lang + "-" + region
+	case lang != "":
+		return lang
+	}
+	return region
+}
+
+// relevantChange 判断修改是否影响指定语言的数据: 与语言无关的字段、该语言的翻译以及中文地区的别名
+func relevantChange(c upstreamChange, language string) bool {
+	if c.language == "" {
+		return true
+	}
+	if c.key == "alternative_titles" {
+		return chineseRegions[c.language]
+	}
+	want := strings.SplitN(language, "-", 2)[0]
+	return strings.SplitN(c.language, "-", 2)[0] == want
+}
+
+// watchMedia 检查全部条目自上次检查以来在TMDB上的修改，并更新状态文件
+// days 为首次检查的条目回溯的天数；allLanguages 为 false 时只报告与配置语言相关的修改
+func (f *TMDBFetcher) watchMedia(statePath string, days int, allLanguages bool) ([]watchReport, int, error) {
+	keys, err := listMediaKeys(f.outputDir)
+	if err != nil {
+		return nil, 0, err
+	}
+	state, err := loadWatchState(statePath)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	var reports []watchReport
+	failures := 0
+	for _, key := range keys {
+		since := now.AddDate(0, 0, -days)
+		if last, ok := state.Titles[key.String()]; ok {
+			t, err := time.Parse(time.RFC3339, last)
+			if err != nil {
+				return nil, 0, fmt.Errorf("状态文件中 %s 的时间格式错误: %v", key, err)
+			}
+			since = t
+		}
+
+		changes, err := f.fetchChanges(key, since, now)
+		if err != nil {
+			// 失败的条目不更新检查时间，下次从原来的时间继续
+			fmt.Fprintf(os.Stderr, "错误: %s: %v\n", key, err)
+			failures++
+			continue
+		}
+		state.Titles[key.String()] = now.Format(time.RFC3339)
+
+		var relevant []upstreamChange
+		for _, c := range changes {
+			if allLanguages || relevantChange(c, f.config.Language) {
+				relevant = append(relevant, c)
+			}
+		}
+		if len(relevant) > 0 {
+			reports = append(reports, watchReport{media: key, title: f.localTitle(key), since: since, changes: relevant})
+		}
+	}
+
+	if err := saveJSON(state, statePath); err != nil {
+		return nil, 0, err
+	}
+	return reports, failures, nil
+}
+
+// localTitle 返回本地维护的标题，使用覆盖文件的条目取快照中的标题
+func (f *TMDBFetcher) localTitle(key mediaKey) string {
+	details, err := loadJSON(filepath.Join(f.mediaDir(key.mediaType, key.mediaID), "details.json"))
+	if err != nil {
+		details, err = f.loadSnapshotFile(key.mediaType, key.mediaID, "details.json")
+	}
+	if err != nil || details == nil {
+		return ""
+	}
+	return mediaTitle(key.mediaType, details)
+}
+
+// printWatchReports 按条目列出TMDB上的修改
+func printWatchReports(w io.Writer, reports []watchReport) {
+	if len(reports) == 0 {
+		fmt.Fprintln(w, "\n✓ 维护的条目在TMDB上没有标题、简介或翻译的修改")
+		return
+	}
+	fmt.Fprintf(w, "\n⚠️  %d 个条目在TMDB上有标题、简介或翻译的修改，请核对:\n", len(reports))
+	for _, r := range reports {
+		fmt.Fprintf(w, "\n%s %s (自 %s)\n", r.media, r.title, r.since.Local().Format("2006-01-02 15:04"))
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "  时间\t字段\t操作\t语言\t内容")
+		for _, c := range r.changes {
+			lang := c.language
+			if lang == "" {
+				lang = "-"
+			}
+			value := c.value
+			if value == nil {
+				value = c.original
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\n", c.time, c.key, c.action, lang, truncateRunes(changeText(value), 60))
+		}
+		tw.Flush()
+	}
+	fmt.Fprintln(w, "\n可使用 refresh 合并TMDB的修改，或使用 diff 查看本地与快照的差异")
+}
+
+// changeText 取出修改值中的文本，翻译只显示标题和简介
+func changeText(v interface{}) string {
+	switch value := v.(type) {
+	case string:
+		return value
+	case map[string]interface{}:
+		if data, ok := value["data"].(map[string]interface{}); ok {
+			var parts []string
+			for _, k := range []string{"title", "name", "overview"} {
+				if s, _ := data[k].(string); s != "" {
+					parts = append(parts, s)
+				}
+			}
+			return strings.Join(parts, " / ")
+		}
+		if s, _ := value["title"].(string); s != "" {
+			return s
+		}
+	}
+	if v == nil {
+		return ""
+	}
+	return formatValue(v)
+}